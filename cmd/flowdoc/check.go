@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/flowdev/ea-flow-doc/gen"
+)
+
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	gf := addGenFlags(fs, "directory the documentation was written to (default: the project directory)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	setupLog(*gf.verbose, stderr)
+
+	dir, files, opts, code := gf.generate("check", fs.Args(), stderr)
+	if code != 0 {
+		return code
+	}
+	if *gf.outDir == "" {
+		*gf.outDir = dir
+	}
+	diffs, err := gen.Check(files, *gf.outDir, opts.Naming)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc check: %v\n", err)
+		return 1
+	}
+	if len(diffs) == 0 {
+		return 0
+	}
+	for _, d := range diffs {
+		fmt.Fprintf(stdout, "%-8s  %s\n", d.State, filepath.ToSlash(d.Path))
+	}
+	fmt.Fprintf(stderr, "flowdoc check: %d files aren't up to date (run 'flowdoc gen' to update them)\n", len(diffs))
+	return 1
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/flowdev/ea-flow-doc/gen"
+)
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	outDir := fs.String("o", "", "directory to write the diff diagrams to (default: no diagrams)")
+	width := fs.Int("width", gen.DefaultWidth, "maximum width of the diagrams")
+	split := fs.Bool("split", false, "split the diagrams into many small SVG files linked in the MarkDown")
+	themeFile := fs.String("theme", "", "JSON file with the theme to use for drawing")
+	formatName := fs.String("format", "svg", "file format of the diagrams: svg, png or pdf")
+	metrics := fs.String("metrics", "font", "measure texts with the widths of the font of the theme (font) or a fixed width per character (fixed)")
+	verbose := fs.Bool("v", false, "log details of parsing the flows")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	setupLog(*verbose, stderr)
+
+	if fs.NArg() != 2 {
+		fmt.Fprintf(stderr, "flowdoc diff: expected the old and the new project directory, got: %q\n", fs.Args())
+		return 2
+	}
+	opts, err := genOptions(*width, *split, *themeFile, *formatName, *metrics, "")
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc diff: %v\n", err)
+		return 2
+	}
+	oldFlows, err := gen.LoadFlows(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc diff: old version: %v\n", err)
+		return 1
+	}
+	newFlows, err := gen.LoadFlows(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc diff: new version: %v\n", err)
+		return 1
+	}
+
+	diffs := gen.DiffFlows(oldFlows, newFlows)
+	for _, d := range diffs {
+		fmt.Fprintf(stdout, "flow %s of package %s:\n", d.Flow, d.Package)
+		for _, change := range d.Changes {
+			fmt.Fprintf(stdout, "    %s\n", change)
+		}
+	}
+	if *outDir != "" {
+		files, err := gen.DiffFiles(diffs, opts)
+		if err != nil {
+			fmt.Fprintf(stderr, "flowdoc diff: %v\n", err)
+			return 1
+		}
+		if err = writeFiles(*outDir, files); err != nil {
+			fmt.Fprintf(stderr, "flowdoc diff: %v\n", err)
+			return 1
+		}
+	}
+	if len(diffs) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func runDraw(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("draw", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	outDir := fs.String("o", ".", "directory to write the diagram and MarkDown files to")
+	themeFile := fs.String("theme", "", "JSON file with the theme to use for drawing")
+	pictures := fs.Bool("pictures", false, "create separate light and dark files for an auto theme")
+	formatName := fs.String("format", "svg", "file format of the diagrams: svg, png or pdf")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintf(stderr, "flowdoc draw: expected exactly one flow file, got: %q\n", fs.Args())
+		return 2
+	}
+
+	format, err := draw.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc draw: %v\n", err)
+		return 2
+	}
+
+	flow, err := draw.ReadFlowFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc draw: %v\n", err)
+		return 1
+	}
+	if *themeFile != "" {
+		theme, err := draw.LoadTheme(*themeFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "flowdoc draw: %v\n", err)
+			return 1
+		}
+		flow.SetTheme(theme)
+	}
+	if *pictures {
+		flow.UsePictures()
+	}
+	flow.SetFormat(format)
+	svgContents, mdContent, err := flow.Draw()
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc draw: %v\n", err)
+		return 1
+	}
+
+	files := make(map[string][]byte, len(svgContents)+1)
+	for name, content := range svgContents {
+		files[name] = content
+	}
+	files[flow.Name()+".md"] = mdContent
+	if err = writeFiles(*outDir, files); err != nil {
+		fmt.Fprintf(stderr, "flowdoc draw: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeFiles writes all files relative to dir.
+// Missing directories are created. Files that would end up outside of dir
+// are rejected.
+func writeFiles(dir string, files map[string][]byte) error {
+	for name, content := range files {
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("file %q would be written outside of directory %q", name, dir)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return fmt.Errorf("unable to create directory for file %q: %w", path, err)
+		}
+		if err := os.WriteFile(path, content, 0666); err != nil {
+			return fmt.Errorf("unable to write file %q: %w", path, err)
+		}
+	}
+	return nil
+}
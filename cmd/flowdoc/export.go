@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/flowdev/ea-flow-doc/export"
+	"github.com/flowdev/ea-flow-doc/gen"
+)
+
+func runExport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "export the flows as JSON")
+	verbose := fs.Bool("v", false, "log details of parsing the flows")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !*asJSON {
+		fmt.Fprintln(stderr, "flowdoc export: please choose an export format (only --json is supported)")
+		return 2
+	}
+	setupLog(*verbose, stderr)
+
+	dir, err := dirArg(fs.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc export: %v\n", err)
+		return 2
+	}
+	flowDatas, err := gen.LoadFlows(dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc export: %v\n", err)
+		return 1
+	}
+	bs, err := export.JSON(flowDatas, dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc export: %v\n", err)
+		return 1
+	}
+	if _, err = stdout.Write(bs); err != nil {
+		fmt.Fprintf(stderr, "flowdoc export: %v\n", err)
+		return 1
+	}
+	return 0
+}
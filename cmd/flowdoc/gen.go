@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+	"github.com/flowdev/ea-flow-doc/gen"
+)
+
+func runGen(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	gf := addGenFlags(fs, "directory to write the documentation to (default: the project directory)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	setupLog(*gf.verbose, stderr)
+
+	dir, files, _, code := gf.generate("gen", fs.Args(), stderr)
+	if code != 0 {
+		return code
+	}
+	if *gf.outDir == "" {
+		*gf.outDir = dir
+	}
+	if err := writeFiles(*gf.outDir, files); err != nil {
+		fmt.Fprintf(stderr, "flowdoc gen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// genFlags are the command line flags for generating flow documentation.
+type genFlags struct {
+	outDir     *string
+	width      *int
+	minWidth   *int
+	bestWidth  *bool
+	responsive *bool
+	split      *bool
+	shared     *bool
+	themeFile  *string
+	formatName *string
+	diagramDir *string
+	index      *bool
+	happyPath  *bool
+	metrics    *string
+	verbose    *bool
+}
+
+func addGenFlags(fs *flag.FlagSet, outDirUsage string) *genFlags {
+	return &genFlags{
+		outDir:     fs.String("o", "", outDirUsage),
+		width:      fs.Int("width", gen.DefaultWidth, "maximum width of the diagrams"),
+		minWidth:   fs.Int("min-width", 400, "minimum width of the diagrams for -best-width and -responsive"),
+		bestWidth:  fs.Bool("best-width", false, "draw with the narrowest width that needs the fewest breaks"),
+		responsive: fs.Bool("responsive", false, "draw with many widths and show the one that fits the screen"),
+		split:      fs.Bool("split", false, "split the diagrams into many small SVG files linked in the MarkDown"),
+		shared:     fs.Bool("shared-tiles", false, "name the split diagrams by their content and share them between all flows"),
+		themeFile:  fs.String("theme", "", "JSON file with the theme to use for drawing"),
+		formatName: fs.String("format", "svg", "file format of the diagrams: svg, png or pdf"),
+		diagramDir: fs.String("diagrams", "", "collect all diagrams in this directory (relative to the output directory)"),
+		index:      fs.Bool("index", false, "add index pages for every package and the project"),
+		happyPath:  fs.Bool("happy-path", false, "hide error paths and mark the components they start at"),
+		metrics:    fs.String("metrics", "font", "measure texts with the widths of the font of the theme (font) or a fixed width per character (fixed)"),
+		verbose:    fs.Bool("v", false, "log details of parsing the flows"),
+	}
+}
+
+// generate generates the documentation of all flows of the project
+// directory in args in memory.
+// It returns the project directory, the generated files, the options used
+// and the exit code of the command.
+func (gf *genFlags) generate(cmd string, args []string, stderr io.Writer) (string, map[string][]byte, gen.Options, int) {
+	dir, err := dirArg(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc %s: %v\n", cmd, err)
+		return "", nil, gen.Options{}, 2
+	}
+	opts, err := genOptions(*gf.width, *gf.split, *gf.themeFile, *gf.formatName, *gf.metrics, *gf.diagramDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc %s: %v\n", cmd, err)
+		return "", nil, opts, 2
+	}
+	opts.SharedTiles = *gf.shared
+	opts.MinWidth = *gf.minWidth
+	opts.BestWidth = *gf.bestWidth
+	opts.Responsive = *gf.responsive
+	if opts.BestWidth && opts.Responsive {
+		fmt.Fprintf(stderr, "flowdoc %s: -best-width and -responsive can't be used together\n", cmd)
+		return "", nil, opts, 2
+	}
+	if opts.Responsive && opts.Mode == draw.FlowModeMDLinks {
+		fmt.Fprintf(stderr, "flowdoc %s: -responsive can't be used with -split\n", cmd)
+		return "", nil, opts, 2
+	}
+	opts.Index = *gf.index
+	opts.HappyPath = *gf.happyPath
+	flowDatas, err := gen.LoadFlows(dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc %s: %v\n", cmd, err)
+		return "", nil, opts, 1
+	}
+	files, err := gen.Files(flowDatas, dir, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc %s: %v\n", cmd, err)
+		return "", nil, opts, 1
+	}
+	return dir, files, opts, 0
+}
+
+// genOptions returns the options for generating flow documentation from
+// the command line flags.
+func genOptions(width int, split bool, themeFile, formatName, metrics, diagramDir string) (gen.Options, error) {
+	opts := gen.Options{Width: width}
+	if split {
+		opts.Mode = draw.FlowModeMDLinks
+	}
+	if diagramDir != "" {
+		opts.Naming = gen.DiagramDirNaming{Dir: filepath.ToSlash(diagramDir)}
+	}
+	if themeFile != "" {
+		theme, err := draw.LoadTheme(themeFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.Theme = theme
+	}
+	format, err := draw.ParseFormat(formatName)
+	if err != nil {
+		return opts, err
+	}
+	opts.Format = format
+	switch metrics {
+	case "font":
+		opts.FontMetrics = true
+	case "fixed":
+	default:
+		return opts, fmt.Errorf("unknown text metrics %q (expected 'font' or 'fixed')", metrics)
+	}
+	return opts, nil
+}
@@ -0,0 +1,100 @@
+// Command flowdoc checks and documents the flows of a Go project.
+//
+// Usage:
+//
+//	flowdoc <command> [flags] [arguments]
+//
+// Run 'flowdoc help' for the list of commands.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// command is a sub-command of flowdoc.
+// It returns the exit code of the program.
+type command struct {
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"check":  {usage: "check [-o outDir] [-width n [-min-width n] [-best-width|-responsive]] [-split [-shared-tiles]] [-theme themeFile.json] [-format svg|png|pdf] [-metrics font|fixed] [-diagrams dir] [-index] [-happy-path] [-v] [dir]", run: runCheck},
+	"diff":   {usage: "diff [-o outDir] [-width n] [-split] [-theme themeFile.json] [-format svg|png|pdf] [-metrics font|fixed] [-v] oldDir newDir", run: runDiff},
+	"draw":   {usage: "draw [-o outDir] [-theme themeFile.json] [-pictures] [-format svg|png|pdf] flowFile.json", run: runDraw},
+	"export": {usage: "export --json [-v] [dir]", run: runExport},
+	"gen":    {usage: "gen [-o outDir] [-width n [-min-width n] [-best-width|-responsive]] [-split [-shared-tiles]] [-theme themeFile.json] [-format svg|png|pdf] [-metrics font|fixed] [-diagrams dir] [-index] [-happy-path] [-v] [dir]", run: runGen},
+	"stats":  {usage: "stats [-limits limitsFile.json] [-v] [dir]", run: runStats},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stdout)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "flowdoc: unknown command %q\n", args[0])
+		printUsage(stderr)
+		return 2
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: flowdoc <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintln(w, "    flowdoc "+commands[name].usage)
+	}
+}
+
+// --------------------------------------------------------------------------
+//
+//	U T I L s :
+//
+// --------------------------------------------------------------------------
+
+// setupLog sends the (very chatty) log output of the parser to stderr
+// in verbose mode and discards it otherwise.
+func setupLog(verbose bool, stderr io.Writer) {
+	if verbose {
+		log.SetOutput(stderr)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+}
+
+// dirArg returns the absolute directory given as the only argument or
+// the current directory if there is none.
+func dirArg(args []string) (string, error) {
+	dir := "."
+	switch len(args) {
+	case 0:
+	case 1:
+		dir = args[0]
+	default:
+		return "", fmt.Errorf("expected at most one directory, got: %q", args)
+	}
+	return filepath.Abs(dir)
+}
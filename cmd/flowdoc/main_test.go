@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rogpeppe/go-internal/testscript"
+)
+
+func TestMain(m *testing.M) {
+	testscript.Main(m, map[string]func(){
+		"flowdoc": func() { main() },
+	})
+}
+
+func TestFlowdoc(t *testing.T) {
+	testscript.Run(t, testscript.Params{
+		Dir: "testdata",
+		// TestWork: true,
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/flowdev/ea-flow-doc/gen"
+)
+
+func runStats(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	limitsFile := fs.String("limits", "", "JSON file with the maximum values of the metrics")
+	verbose := fs.Bool("v", false, "log details of parsing the flows")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	setupLog(*verbose, stderr)
+
+	dir, err := dirArg(fs.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc stats: %v\n", err)
+		return 2
+	}
+	var limits gen.Limits
+	if *limitsFile != "" {
+		if limits, err = gen.LoadLimits(*limitsFile); err != nil {
+			fmt.Fprintf(stderr, "flowdoc stats: %v\n", err)
+			return 2
+		}
+	}
+	flowDatas, err := gen.LoadFlows(dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "flowdoc stats: %v\n", err)
+		return 1
+	}
+
+	stats := gen.FlowStats(flowDatas)
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Package\tFlow\tSteps\tDepth\tPorts\tPlugins\tFanIn\tFanOut\tLongestPath\t")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n",
+			s.Package, s.Flow, s.Steps, s.Depth, s.Ports, s.Plugins, s.FanIn, s.FanOut, s.LongestPath)
+	}
+	if err = tw.Flush(); err != nil {
+		fmt.Fprintf(stderr, "flowdoc stats: %v\n", err)
+		return 1
+	}
+
+	violations := limits.Check(stats)
+	if len(violations) == 0 {
+		return 0
+	}
+	for _, v := range violations {
+		fmt.Fprintf(stderr, "flowdoc stats: %v\n", v)
+	}
+	fmt.Fprintf(stderr, "flowdoc stats: %d metrics exceed their limits\n", len(violations))
+	return 1
+}
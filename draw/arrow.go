@@ -26,11 +26,15 @@ type DataType struct {
 type Arrow struct {
 	withDrawData
 	dataTypes         []*DataType
+	notes             []string
+	isError           bool
+	change            Change
 	srcPort           string
 	dstPort           string
 	srcComp           StartComp
 	dstComp           EndComp
-	dataTypesWidth    int  // for centering the data types
+	dataTypesWidth    int // for centering the data types
+	notesWidth        int
 	maxWidthRespected bool // remember that respectMaxWidth has been called already
 }
 
@@ -95,7 +99,7 @@ func (arr *Arrow) calcHorizontalValues(x0 int) {
 func (arr *Arrow) calcWidth() int {
 	arr.calcDataTypesWidth()
 
-	portWidth := len(arr.srcPort)*CharWidth + len(arr.dstPort)*CharWidth
+	portWidth := arr.textWidth(arr.srcPort, true) + arr.textWidth(arr.dstPort, true)
 
 	if portWidth != 0 {
 		portWidth += WordGap + // so the port text isn't glued to the comp
@@ -103,7 +107,8 @@ func (arr *Arrow) calcWidth() int {
 		// ... clear which type a single port is
 	}
 
-	return max(portWidth, arr.dataTypesWidth) + arrTipWidth
+	arr.notesWidth = notesWidth(arr.metrics, arr.notes)
+	return max(portWidth, arr.dataTypesWidth, arr.notesWidth) + arrTipWidth
 }
 
 func (arr *Arrow) calcDataTypesWidth() {
@@ -113,7 +118,7 @@ func (arr *Arrow) calcDataTypesWidth() {
 
 	w1 := 1
 	for _, dt := range arr.dataTypes {
-		calcDataTypeWidth(dt)
+		calcDataTypeWidth(arr.metrics, dt)
 		w1 = max(w1, dt.w1)
 	}
 	width := w1
@@ -128,9 +133,9 @@ func (arr *Arrow) calcDataTypesWidth() {
 	arr.dataTypesWidth = width
 }
 
-func calcDataTypeWidth(dt *DataType) {
-	dt.w1 = ParenWidth + (1+len(dt.name)+1)*CharWidth
-	dt.drawData.width = dt.w1 + (len(dt.typ)+1)*CharWidth + ParenWidth
+func calcDataTypeWidth(tm TextMetrics, dt *DataType) {
+	dt.w1 = ParenWidth + CharWidth + textWidth(tm, dt.name, false) + CharWidth
+	dt.drawData.width = dt.w1 + textWidth(tm, dt.typ, false) + CharWidth + ParenWidth
 }
 
 func (arr *Arrow) extendArrows() {
@@ -167,6 +172,7 @@ func (arr *Arrow) respectMaxWidth(maxWidth, num int) (newStartComps []StartComp,
 		}
 
 		brk := NewBreakStart(num)
+		brk.metrics = arr.metrics
 		arr.AddDestination(brk)
 		arr.calcHorizontalValues(x0)
 		_, _, newWidth = brk.respectMaxWidth(maxWidth, num+1)
@@ -190,11 +196,11 @@ func (arr *Arrow) minRestOfRowWidth(num int) int {
 	width := 0
 	if len(arr.srcPort) > 0 {
 		width += WordGap + // so the port text isn't glued to the comp
-			len(arr.srcPort)*CharWidth +
+			arr.textWidth(arr.srcPort, true) +
 			2*CharWidth // so it is clear which type a single port is
 	}
 
-	return width + arrTipWidth + breakWidth(num)
+	return width + arrTipWidth + breakWidth(arr.metrics, num)
 }
 
 func (arr *Arrow) breakable() bool {
@@ -207,7 +213,7 @@ func (arr *Arrow) brokenWidths(num int) (longBroken, unBroken int) {
 		return -1, -1
 	}
 
-	longBroken = max(arr.dataTypesWidth+arrTipWidth+breakWidth(num), arr.minRestOfRowWidth(num))
+	longBroken = max(max(arr.dataTypesWidth, arr.notesWidth)+arrTipWidth+breakWidth(arr.metrics, num), arr.minRestOfRowWidth(num))
 	unBroken = arr.drawData.width + arr.dstComp.minRestOfRowWidth(num)
 
 	return longBroken, unBroken
@@ -219,11 +225,17 @@ func (arr *Arrow) brokenWidths(num int) (longBroken, unBroken int) {
 func (arr *Arrow) breakShort() *Arrow {
 	newArr := &Arrow{
 		dataTypes: arr.dataTypes,
+		notes:     arr.notes,
+		isError:   arr.isError,
+		change:    arr.change,
 		dstPort:   arr.dstPort,
 	}
+	newArr.metrics = arr.metrics
 
 	arr.dstComp.switchInput(arr, newArr)
 	arr.dataTypes = nil
+	arr.notes = nil
+	arr.notesWidth = 0
 	arr.dstPort = ""
 	arr.dstComp = nil
 	arr.dataTypesWidth = 0
@@ -238,6 +250,7 @@ func (arr *Arrow) breakLong() *Arrow {
 	newArr := &Arrow{
 		dstPort: arr.dstPort,
 	}
+	newArr.metrics = arr.metrics
 
 	arr.dstComp.switchInput(arr, newArr)
 	arr.dstPort = ""
@@ -260,9 +273,9 @@ func (arr *Arrow) calcVerticalValues(y0, minLine int, mode FlowMode) (maxLines,
 		return ad.maxLines(), ad.ymax()
 	}
 
-	y := y0
-	height := 0
-	lines := 0
+	height := len(arr.notes) * LineHeight // the notes come first
+	lines := len(arr.notes)
+	y := y0 + height
 	for _, dt := range arr.dataTypes {
 		calcDataTypeVerticals(dt, y, minLine+lines)
 		dtd := dt.drawData
@@ -331,7 +344,7 @@ func (arr *Arrow) arrowToSVG(smf *svgMDFlow, line int, mode FlowMode) {
 		srcPortToSVG(svg, arr, ad)
 		dstPortToSVG(svg, arr, ad)
 
-		arrToSVG(svg, ad)
+		arrToSVG(svg, ad, arr.isError, arr.change)
 
 		smf.lastX += ad.width
 		return
@@ -339,9 +352,15 @@ func (arr *Arrow) arrowToSVG(smf *svgMDFlow, line int, mode FlowMode) {
 		dataWidth := ad.width - arrTipWidth
 		lastIdx := len(arr.dataTypes) - 1
 		idx := line - ad.minLine
+		if idx < len(arr.notes) {
+			noteToSVG(svg, arr.metrics, ad.x0, ad.y0+idx*LineHeight, arr.notes[idx])
+			smf.lastX += ad.width
+			return
+		}
+		idx -= len(arr.notes)
 		dt := arr.dataTypes[idx]
 
-		arrowDataTypeToSVG(svg, link, dt, ad.x0, dataWidth, arr.dataTypesWidth,
+		arrowDataTypeToSVG(svg, link, arr.metrics, dt, ad.x0, dataWidth, arr.dataTypesWidth,
 			idx == 0, idx == lastIdx)
 	}
 
@@ -356,7 +375,10 @@ func svgDimensionsForLine(line int, arrow *Arrow, ad *drawData, maxLine int,
 	}
 
 	idx := line - ad.minLine
-	dt := arrow.dataTypes[idx]
+	if idx < len(arrow.notes) {
+		return ad.x0, ad.y0 + idx*LineHeight, LineHeight, ad.width
+	}
+	dt := arrow.dataTypes[idx-len(arrow.notes)]
 	dtd := dt.drawData
 	return ad.x0, dtd.y0, dtd.height, ad.width
 }
@@ -366,7 +388,7 @@ func srcPortToSVG(svg *svgFlow, arrow *Arrow, ad *drawData) {
 		svg.Texts = append(svg.Texts, &svgText{
 			X:     ad.x0 + WordGap,
 			Y:     ad.ymax() - arrSmallTextOffset,
-			Width: len(arrow.srcPort) * CharWidth,
+			Width: arrow.textWidth(arrow.srcPort, true),
 			Text:  arrow.srcPort,
 			Small: true,
 		})
@@ -375,7 +397,7 @@ func srcPortToSVG(svg *svgFlow, arrow *Arrow, ad *drawData) {
 
 func dstPortToSVG(svg *svgFlow, arrow *Arrow, ad *drawData) {
 	if arrow.dstPort != "" {
-		w := len(arrow.dstPort) * CharWidth
+		w := arrow.textWidth(arrow.dstPort, true)
 		svg.Texts = append(svg.Texts, &svgText{
 			X:     ad.x0 + ad.width - w - arrTipWidth,
 			Y:     ad.ymax() - arrSmallTextOffset,
@@ -386,23 +408,25 @@ func dstPortToSVG(svg *svgFlow, arrow *Arrow, ad *drawData) {
 	}
 }
 
-func arrToSVG(svg *svgFlow, ad *drawData) {
+func arrToSVG(svg *svgFlow, ad *drawData, isError bool, change Change) {
 
 	arrY := ad.ymax() - LineHeight + arrTipHeight
 	svg.Arrows = append(svg.Arrows, &svgArrow{
-		X1:    ad.x0,
-		Y1:    arrY,
-		X2:    ad.x0 + ad.width,
-		Y2:    arrY,
-		XTip1: ad.x0 + ad.width - arrTipHeight,
-		YTip1: arrY - arrTipHeight,
-		XTip2: ad.x0 + ad.width - arrTipHeight,
-		YTip2: arrY + arrTipHeight,
+		X1:     ad.x0,
+		Y1:     arrY,
+		X2:     ad.x0 + ad.width,
+		Y2:     arrY,
+		XTip1:  ad.x0 + ad.width - arrTipHeight,
+		YTip1:  arrY - arrTipHeight,
+		XTip2:  ad.x0 + ad.width - arrTipHeight,
+		YTip2:  arrY + arrTipHeight,
+		Error:  isError,
+		Change: change,
 	})
 }
 
 func arrowDataTypeToSVG(
-	svg *svgFlow, link *svgLink, dt *DataType,
+	svg *svgFlow, link *svgLink, tm TextMetrics, dt *DataType,
 	x0, width, dataTypesWidth int,
 	first, last bool,
 ) {
@@ -424,13 +448,13 @@ func arrowDataTypeToSVG(
 	svg.Texts = append(svg.Texts, &svgText{
 		X:     x0 + padding,
 		Y:     y,
-		Width: len(dt.name) * CharWidth,
+		Width: textWidth(tm, dt.name, false),
 		Text:  dt.name,
 		Link:  dt.link != "",
 	})
 
 	typText := dt.typ
-	typWidth := len(dt.typ) * CharWidth
+	typWidth := textWidth(tm, dt.typ, false)
 	if last {
 		typText += ")"
 		typWidth += ParenWidth
@@ -36,7 +36,7 @@ func (brk *BreakStart) minRestOfRowWidth(num int) int {
 }
 
 func (brk *BreakStart) calcHorizontalValues(x0 int) {
-	brk.drawData = breakHorizontalValues(x0, brk.number)
+	brk.drawData = breakHorizontalValues(brk.metrics, x0, brk.number)
 }
 
 func (brk *BreakStart) extendArrows() {
@@ -58,6 +58,7 @@ func (brk *BreakStart) End() *BreakEnd {
 		brk.end = &BreakEnd{
 			number: brk.number,
 		}
+		brk.end.metrics = brk.metrics
 	}
 	return brk.end
 }
@@ -93,7 +94,7 @@ func (brk *BreakEnd) minRestOfRowWidth(num int) int {
 }
 
 func (brk *BreakEnd) calcHorizontalValues(x0 int) {
-	brk.withDrawData.drawData = breakHorizontalValues(x0, brk.number)
+	brk.withDrawData.drawData = breakHorizontalValues(brk.metrics, x0, brk.number)
 	brk.output.calcHorizontalValues(brk.drawData.x0 + brk.drawData.width)
 }
 
@@ -128,8 +129,8 @@ func (brk *BreakEnd) toSVG(smf *svgMDFlow, line int, mode FlowMode) {
 // --------------------------------------------------------------------------
 // Helpers:
 // --------------------------------------------------------------------------
-func breakHorizontalValues(x0, num int) *drawData {
-	return newDrawData(x0, breakWidth(num))
+func breakHorizontalValues(tm TextMetrics, x0, num int) *drawData {
+	return newDrawData(x0, breakWidth(tm, num))
 }
 
 func breakVerticalValues(d *drawData, ymax, maxLines int) {
@@ -139,30 +140,8 @@ func breakVerticalValues(d *drawData, ymax, maxLines int) {
 	d.lines = 1
 }
 
-func breakWidth(num int) int {
-	return BreakWidth + numWidth(num)
-}
-
-// numWidth returns the width to the given number.
-// It panics for negative numbers and numbers bigger than 9,999.
-func numWidth(num int) int {
-	if num < 0 {
-		panic("unable to calculate the width of a negative number")
-	}
-
-	if num < 10 {
-		return CharWidth
-	}
-	if num < 100 {
-		return 2 * CharWidth
-	}
-	if num < 1000 {
-		return 3 * CharWidth
-	}
-	if num < 10_000 {
-		return 4 * CharWidth
-	}
-	panic("unable to calculate the width of a number bigger than 9,999")
+func breakWidth(tm TextMetrics, num int) int {
+	return BreakWidth + textWidth(tm, strconv.Itoa(num), false)
 }
 
 func breakToSVG(smf *svgMDFlow, line int, mode FlowMode, bd *drawData, number int) {
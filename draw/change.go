@@ -0,0 +1,101 @@
+package draw
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// Change tells how a component or arrow differs from another version of
+// the flow.
+type Change int
+
+// The changes of a component or arrow.
+const (
+	Unchanged Change = iota
+	Added            // drawn in the GoLink color of the theme (green)
+	Removed          // drawn in the Error color of the theme (red)
+)
+
+var changeNames = []string{"unchanged", "added", "removed"}
+
+func (c Change) String() string {
+	if c < 0 || int(c) >= len(changeNames) {
+		return fmt.Sprintf("Change(%d)", int(c))
+	}
+	return changeNames[c]
+}
+
+// ParseChange returns the change with the given name.
+// The empty name is Unchanged.
+func ParseChange(name string) (Change, error) {
+	if name == "" {
+		return Unchanged, nil
+	}
+	for i, n := range changeNames {
+		if n == name {
+			return Change(i), nil
+		}
+	}
+	return Unchanged, fmt.Errorf("unknown change %q (expected one of: %v)", name, changeNames)
+}
+
+// MarkChange marks the component as added or removed.
+// Its outline is drawn in the color of the change.
+func (comp *Comp) MarkChange(change Change) *Comp {
+	comp.change = change
+	return comp
+}
+
+// MarkChange marks the arrow as added or removed.
+// It is drawn in the color of the change.
+func (arr *Arrow) MarkChange(change Change) *Arrow {
+	arr.change = change
+	return arr
+}
+
+// Color returns the color of the change in the theme.
+// Unchanged parts use the text color.
+func (c Change) Color(t *Theme) string {
+	switch c {
+	case Added:
+		return t.GoLink
+	case Removed:
+		return t.Error
+	default:
+		return t.Text
+	}
+}
+
+// Stroke returns the color of the arrow.
+func (a *svgArrow) Stroke(t *Theme) string {
+	if a.Error && a.Change == Unchanged {
+		return t.Error
+	}
+	return a.Change.Color(t)
+}
+
+// Stroke returns the color of the path.
+func (p *svgPath) Stroke(t *Theme) string {
+	if p.Error && p.Change == Unchanged {
+		return t.Error
+	}
+	return p.Change.Color(t)
+}
+
+// Stroke returns the color of the outline of the shape.
+func (r *svgRect) Stroke(t *Theme) string {
+	return r.Change.Color(t)
+}
+
+// strokeColor returns the parsed color of the change or col for unchanged
+// parts.
+func (c Change) strokeColor(colors *themeColors, col color.RGBA) color.RGBA {
+	switch c {
+	case Added:
+		return colors.goLink
+	case Removed:
+		return colors.error
+	default:
+		return col
+	}
+}
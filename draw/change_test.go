@@ -0,0 +1,37 @@
+package draw_test
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestDrawChangesPNG(t *testing.T) {
+	pi := drawPNGImage(t, archiveFlow(t, "change.txt", "changes.json"), 0, 0, 221, 104)
+
+	text := color.RGBA{A: 255}
+	added := color.RGBA{G: 96, A: 255}
+	removed := color.RGBA{R: 192, A: 255}
+	pi.checkColor(t, "added arrow", 76, 8, added)
+	pi.checkColor(t, "removed arrow", 143, 8, removed)
+	pi.checkColor(t, "unchanged arrow", 100, 64, text)
+	pi.checkColor(t, "border of added component", 113, 47, added)
+	pi.checkColor(t, "border of removed component", 126, 80, removed)
+}
+
+func TestDrawChangesPDF(t *testing.T) {
+	pdf := drawFile(t, archiveFlow(t, "change.txt", "changes.json"), draw.FormatPDF)
+
+	for _, expected := range []string{
+		"\nq 0 0.376 0 RG\n73 8 m 86 8 l S\n",
+		"\nq 0.753 0 0 RG\n140 8 m 153 8 l S\n",
+		"\nq 0 0.376 0 RG\n0.376 0.753 1 rg 2 w\n96 1 m\n",
+		"\nq 0.753 0 0 RG\n0.376 0.753 1 rg 2 w\n136 57 m\n",
+	} {
+		if !bytes.Contains(pdf, []byte(expected)) {
+			t.Errorf("expected PDF to contain %q, got:\n%s", expected, pdf)
+		}
+	}
+}
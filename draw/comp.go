@@ -11,6 +11,12 @@ type Comp struct {
 	typ               string
 	link              string
 	goLink            bool
+	kind              CompKind
+	class             string
+	style             string
+	notes             []string
+	errorMarker       bool // error paths are hidden
+	change            Change
 	plugins           []*PluginGroup
 	inputs            []*Arrow
 	outputs           []*Arrow
@@ -140,41 +146,53 @@ func (comp *Comp) calcWidth(x0 int) int {
 		return comp.drawData.width
 	}
 
-	width := comp.calcMainWidth()
+	width := comp.calcPluginsWidth(x0, comp.calcMainWidth())
+	comp.setPluginWidths(width)
+	return width
+}
+
+// calcPluginsWidth calculates the horizontal values of all plugins and
+// returns the maximum of their widths and the given width.
+func (comp *Comp) calcPluginsWidth(x0, width int) int {
 	for _, p := range comp.plugins {
-		calcPluginHorizontals(p, x0)
+		calcPluginHorizontals(comp.metrics, p, x0)
 		pd := p.drawData
 		width = max(width, pd.width)
 	}
+	return width
+}
 
+func (comp *Comp) setPluginWidths(width int) {
 	for _, p := range comp.plugins {
 		p.drawData.width = width
 		for _, pt := range p.types {
 			pt.drawData.width = width
 		}
 	}
-
-	return width
 }
 
 func (comp *Comp) calcMainWidth() int {
-	l := max(len(comp.name), len(comp.typ))
-	width := WordGap + l*CharWidth + WordGap
+	l := max(comp.textWidth(comp.name, false), comp.textWidth(comp.typ, false))
+	left, right := kindPadding(comp.kind)
+	width := left + max(WordGap+l+WordGap, notesWidth(comp.metrics, comp.notes)) + right
+	if comp.errorMarker {
+		width += 2*markerRadius + WordGap/2
+	}
 
 	return width
 }
 
-func calcPluginHorizontals(p *PluginGroup, x0 int) {
+func calcPluginHorizontals(tm TextMetrics, p *PluginGroup, x0 int) {
 	height := 0
 	width := 0
 	lines := 0
 	if p.title != "" {
 		height += LineHeight
-		width = WordGap + (len(p.title)+1)*CharWidth + WordGap // title text and padding
+		width = WordGap + textWidth(tm, p.title+":", false) + WordGap // title text and padding
 		lines++
 	}
 	for _, t := range p.types {
-		calcPluginTypeDimensions(t, x0)
+		calcPluginTypeDimensions(tm, t, x0)
 		td := t.drawData
 		height += td.height
 		width = max(width, td.width)
@@ -188,8 +206,8 @@ func calcPluginHorizontals(p *PluginGroup, x0 int) {
 	}
 }
 
-func calcPluginTypeDimensions(pt *Plugin, x0 int) {
-	width := WordGap + len(pt.typ)*CharWidth + WordGap
+func calcPluginTypeDimensions(tm TextMetrics, pt *Plugin, x0 int) {
+	width := WordGap + textWidth(tm, pt.typ, false) + WordGap
 	pt.drawData = &drawData{
 		x0:     x0,
 		width:  width,
@@ -259,6 +277,8 @@ func (comp *Comp) calcVerticalValues(y0, minLine int, mode FlowMode) (maxLines,
 		height += pd.height
 		lines += pd.lines
 	}
+	height += len(comp.notes) * LineHeight
+	lines += len(comp.notes)
 
 	cd.height = height
 	cd.lines = lines
@@ -357,19 +377,20 @@ func (comp *Comp) allToSVG(smf *svgMDFlow, line int, mode FlowMode) {
 		svg = smf.svgs[""]
 	}
 
-	if mode == FlowModeMDLinks || idx == 0 { // outer rect
-		rectToSVG(svg, cd, false, false, false)
+	if mode == FlowModeMDLinks || idx == 0 { // outer shape
+		comp.shapeToSVG(svg, cd)
 	}
 	if comp.mainToSVG(svg, link, line) { // main data type
 		smf.lastX += cd.width
 		return
 	}
 	for _, p := range comp.plugins {
-		if pluginGroupToSVG(svg, link, line, mode, p) {
+		if pluginGroupToSVG(svg, link, line, mode, comp.metrics, p) {
 			smf.lastX += cd.width
 			return
 		}
 	}
+	comp.notesToSVG(svg, line)
 	if link != nil {
 		link.Link = comp.link
 	}
@@ -384,12 +405,16 @@ func (comp *Comp) mainToSVG(svg *svgFlow, link *svgLink, line int) bool {
 	}
 	y0 := md.y0
 	idx := line - md.minLine
+	if idx == 0 {
+		comp.errorMarkerToSVG(svg)
+	}
+	left, _ := kindPadding(comp.kind)
 	if comp.name != "" {
 		if idx == 0 {
 			svg.Texts = append(svg.Texts, &svgText{
-				X:      md.x0 + WordGap,
+				X:      md.x0 + left + WordGap,
 				Y:      y0 + LineHeight - TextOffset,
-				Width:  len(comp.name) * CharWidth,
+				Width:  comp.textWidth(comp.name, false),
 				Text:   comp.name,
 				Link:   !comp.goLink && comp.link != "",
 				GoLink: comp.goLink,
@@ -401,9 +426,9 @@ func (comp *Comp) mainToSVG(svg *svgFlow, link *svgLink, line int) bool {
 	}
 	if idx == 0 {
 		svg.Texts = append(svg.Texts, &svgText{
-			X:      md.x0 + WordGap,
+			X:      md.x0 + left + WordGap,
 			Y:      y0 + LineHeight - TextOffset,
-			Width:  len(comp.typ) * CharWidth,
+			Width:  comp.textWidth(comp.typ, false),
 			Text:   comp.typ,
 			Link:   !comp.goLink && comp.link != "",
 			GoLink: comp.goLink,
@@ -440,7 +465,7 @@ func rectToSVG(svg *svgFlow, d *drawData, plugin, subRect, last bool) {
 	svg.Rects = append(svg.Rects, rect)
 }
 
-func pluginGroupToSVG(svg *svgFlow, link *svgLink, line int, mode FlowMode, p *PluginGroup) bool {
+func pluginGroupToSVG(svg *svgFlow, link *svgLink, line int, mode FlowMode, tm TextMetrics, p *PluginGroup) bool {
 	pd := p.drawData
 	if !pd.contains(line) {
 		return false
@@ -454,20 +479,20 @@ func pluginGroupToSVG(svg *svgFlow, link *svgLink, line int, mode FlowMode, p *P
 		svg.Texts = append(svg.Texts, &svgText{
 			X:     pd.x0 + WordGap,
 			Y:     pd.y0 + LineHeight - TextOffset,
-			Width: len(txt) * CharWidth,
+			Width: textWidth(tm, txt, false),
 			Text:  txt,
 		})
 		return true
 	}
 	for _, pt := range p.types {
-		if pluginToSVG(svg, link, line, pt) {
+		if pluginToSVG(svg, link, line, tm, pt) {
 			return true
 		}
 	}
 	return true // should never happen
 }
 
-func pluginToSVG(svg *svgFlow, link *svgLink, line int, pt *Plugin) bool {
+func pluginToSVG(svg *svgFlow, link *svgLink, line int, tm TextMetrics, pt *Plugin) bool {
 	ptd := pt.drawData
 	if !ptd.contains(line) {
 		return false
@@ -479,7 +504,7 @@ func pluginToSVG(svg *svgFlow, link *svgLink, line int, pt *Plugin) bool {
 	svg.Texts = append(svg.Texts, &svgText{
 		X:      ptd.x0 + WordGap,
 		Y:      ptd.y0 + LineHeight - TextOffset,
-		Width:  len(pt.typ) * CharWidth,
+		Width:  textWidth(tm, pt.typ, false),
 		Text:   pt.typ,
 		Link:   !pt.goLink && pt.link != "",
 		GoLink: pt.goLink,
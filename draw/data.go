@@ -95,6 +95,7 @@ func (d *drawData) drawLine(line int) bool {
 
 type withDrawData struct {
 	drawData *drawData
+	metrics  TextMetrics // set while copying the flow for drawing
 }
 
 func (wd *withDrawData) getDrawData() *drawData {
@@ -103,6 +104,9 @@ func (wd *withDrawData) getDrawData() *drawData {
 func (wd *withDrawData) resetDrawData() {
 	wd.drawData = nil
 }
+func (wd *withDrawData) textWidth(text string, small bool) int {
+	return textWidth(wd.metrics, text, small)
+}
 
 type CompRegistry interface {
 	register(*Comp)
@@ -112,6 +116,7 @@ type CompRegistry interface {
 type Cluster struct {
 	withDrawData
 	starts []StartComp
+	layers [][]anyComp // only used by the layered layout
 }
 
 func NewCluster() *Cluster {
@@ -198,30 +203,89 @@ func (cl *Cluster) toSVG(smf *svgMDFlow, line int, mode FlowMode) {
 
 type Flow struct {
 	withDrawData
-	name         string
-	mode         FlowMode
-	width        int
-	dark         bool
-	starts       []StartComp
-	clusters     []*Cluster
-	compRegistry map[string]*Comp
-}
-
-func NewFlow(name string, mode FlowMode, width int, dark bool) *Flow {
+	name             string
+	mode             FlowMode
+	width            int
+	theme            *Theme
+	pictures         bool
+	metrics          TextMetrics
+	direction        LayoutDirection
+	layered          bool
+	routing          bool
+	happyPath        bool
+	format           Format
+	naming           Naming
+	sharedTiles      bool
+	description      string
+	portDescriptions []portDescription
+	starts           []StartComp
+	clusters         []*Cluster
+	compRegistry     map[string]*Comp
+	duplicateIDs     []string
+	groups           []*Group
+	copies           map[*Comp]*Comp  // only set while laying out
+	happyComps       map[anyComp]bool // only set while laying out the happy path
+}
+
+// NewFlow creates a new flow that can be drawn with the given theme.
+// If theme is nil, the LightTheme is used.
+func NewFlow(name string, mode FlowMode, width int, theme *Theme) *Flow {
 	return &Flow{
 		name:         name,
 		mode:         mode,
 		width:        width,
-		dark:         dark,
+		theme:        themeOrDefault(theme),
 		compRegistry: make(map[string]*Comp, 128),
 	}
 }
 
-func (flow *Flow) ChangeConfig(name string, mode FlowMode, width int, dark bool) {
+func (flow *Flow) ChangeConfig(name string, mode FlowMode, width int, theme *Theme) {
 	flow.name = name
 	flow.mode = mode
 	flow.width = width
-	flow.dark = dark
+	flow.theme = themeOrDefault(theme)
+}
+
+// SetTheme changes the theme used for drawing the flow.
+// If theme is nil, the LightTheme is used.
+func (flow *Flow) SetTheme(theme *Theme) *Flow {
+	flow.theme = themeOrDefault(theme)
+	return flow
+}
+
+// UsePictures lets a flow with an AutoTheme create separate SVG files for
+// the light and dark color scheme. They are combined in the MarkDown file
+// with a <picture> element.
+func (flow *Flow) UsePictures() *Flow {
+	flow.pictures = true
+	return flow
+}
+
+// SetTextMetrics changes the metrics used for measuring all texts of the
+// flow. If metrics is nil, FixedMetrics are used.
+func (flow *Flow) SetTextMetrics(metrics TextMetrics) *Flow {
+	flow.metrics = metrics
+	return flow
+}
+
+// SetDirection changes the direction in which the flow is laid out.
+func (flow *Flow) SetDirection(dir LayoutDirection) *Flow {
+	flow.direction = dir
+	return flow
+}
+
+// textMetrics returns the metrics of the flow with the font sizes of its
+// theme filled in for a zero FontMetrics.
+func (flow *Flow) textMetrics() TextMetrics {
+	if fm, ok := flow.metrics.(FontMetrics); ok && fm == (FontMetrics{}) {
+		return NewFontMetrics(flow.theme)
+	}
+	return flow.metrics
+}
+
+// Name returns the name of the flow.
+func (flow *Flow) Name() string {
+	return flow.name
 }
 
 func (flow *Flow) AddStart(comp StartComp) *Flow {
@@ -232,48 +296,13 @@ func (flow *Flow) AddStart(comp StartComp) *Flow {
 // Draw creates a set of SVG diagrams and a MarkDown file for this flow.
 // If the flow data isn't valid or the SVG diagrams or the MarkDown file
 // can't be created with their template, an error is returned.
+// Draw doesn't change the flow, so it can be drawn many times.
 func (flow *Flow) Draw() (svgContents map[string][]byte, mdContent []byte, err error) {
-	err = flow.validate()
-	if err != nil {
-		return nil, nil, err
-	}
-
-	flow.copyAllClusters()
-	flow.calcHorizontalValues()
-	flow.extendArrows()
-	flow.respectMaxWidth()
-	flow.calcVerticalValues()
-
-	smf := flowToSVGs(flow)
-	if flow.mode != FlowModeMDLinks {
-		svgName := smf.svgFilePrefix + ".svg"
-		smf.svgs[svgName] = smf.svgs[""]
-		delete(smf.svgs, "")
-		smf.md.FlowLines = append(smf.md.FlowLines, make([]*svgLink, 1))
-		smf.md.FlowLines[0][0] = &svgLink{
-			Name: flow.name,
-			SVG:  svgName,
-		}
-	}
-
-	svgContents, err = svgFlowsToBytes(smf.svgs, flow.dark)
+	layout, err := flow.Layout(flow.LayoutOptions())
 	if err != nil {
 		return nil, nil, err
 	}
-	mdContent, err = mdFlowToBytes(smf.md)
-	if err != nil {
-		return nil, nil,
-			fmt.Errorf("unable to create MarkDown content for %q flow: %w", flow.name, err)
-	}
-	return svgContents, mdContent, nil
-}
-
-func (flow *Flow) validate() error {
-	if len(flow.starts) == 0 {
-		return fmt.Errorf("nothing to draw in the flow")
-	}
-
-	return nil
+	return layout.Render(flow.RenderOptions())
 }
 
 func (flow *Flow) AddCluster(cl *Cluster) *Flow {
@@ -286,6 +315,9 @@ func (flow *Flow) lookup(id string) *Comp {
 }
 
 func (flow *Flow) register(comp *Comp) {
+	if old, ok := flow.compRegistry[comp.ID()]; ok && old != comp {
+		flow.duplicateIDs = append(flow.duplicateIDs, comp.ID())
+	}
 	flow.compRegistry[comp.ID()] = comp
 }
 
@@ -367,12 +399,14 @@ func (flow *Flow) copyComp(
 	switch src := comp.(type) {
 	case *BreakStart:
 		dst := NewBreakStart(src.number)
+		dst.metrics = flow.textMetrics()
 		cache[comp] = dst
 		breakCache[src.number] = dst
 		dst.addInput(flow.copyArrow(src.input, cl, false, cache, breakCache))
 		return dst
 	case *BreakEnd:
 		dst := breakCache[src.number].End()
+		dst.metrics = flow.textMetrics()
 		cache[comp] = dst
 		cl.starts = append(cl.starts, dst)
 		dst.AddOutput(flow.copyArrow(src.output, cl, true, cache, breakCache))
@@ -380,7 +414,15 @@ func (flow *Flow) copyComp(
 	case *Comp:
 		dst := NewComp(src.name, src.typ, src.link, nil)
 		dst.goLink = src.goLink
+		dst.kind, dst.class, dst.style = src.kind, src.class, src.style
+		dst.change = src.change
+		dst.notes = src.notes
+		dst.errorMarker = flow.hasHiddenErrors(src)
+		dst.metrics = flow.textMetrics()
 		cache[comp] = dst
+		if flow.copies != nil {
+			flow.copies[src] = dst
+		}
 		for _, srcpg := range src.plugins {
 			dstpg := NewPluginGroup(srcpg.title)
 			for _, srcp := range srcpg.types {
@@ -390,14 +432,15 @@ func (flow *Flow) copyComp(
 			}
 			dst.AddPluginGroup(dstpg)
 		}
-		if len(src.inputs) <= 0 {
+		inputs, outputs := flow.keptArrows(src.inputs), flow.keptArrows(src.outputs)
+		if len(inputs) <= 0 {
 			cl.starts = append(cl.starts, dst)
 		}
 		if inArr != nil {
 			dst.addInput(inArr)
 		}
 	INPUTS:
-		for _, in := range src.inputs {
+		for _, in := range inputs {
 			arr := flow.copyArrow(in, cl, false, cache, breakCache)
 			for _, dstin := range dst.inputs {
 				if dstin == arr {
@@ -406,14 +449,14 @@ func (flow *Flow) copyComp(
 			}
 			dst.addInput(arr)
 		}
-		for i, in := range src.inputs { // order inputs correctly
+		for i, in := range inputs { // order inputs correctly
 			dst.inputs[i] = cache[in].(*Arrow)
 		}
 		if outArr != nil {
 			dst.AddOutput(outArr)
 		}
 	OUTPUTS:
-		for _, out := range src.outputs {
+		for _, out := range outputs {
 			arr := flow.copyArrow(out, cl, true, cache, breakCache)
 			for _, dstout := range dst.outputs {
 				if dstout == arr {
@@ -422,24 +465,27 @@ func (flow *Flow) copyComp(
 			}
 			dst.AddOutput(arr)
 		}
-		for i, out := range src.outputs { // order outputs correctly
+		for i, out := range outputs { // order outputs correctly
 			dst.outputs[i] = cache[out].(*Arrow)
 		}
 		return dst
 	case *Loop:
 		dst := NewLoop(src.name, src.port, src.link)
 		dst.goLink = src.goLink
+		dst.metrics = flow.textMetrics()
 		cache[comp] = dst
 		dst.addInput(flow.copyArrow(src.input, cl, false, cache, breakCache))
 		return dst
 	case *StartPort:
 		dst := NewStartPort(src.name)
+		dst.metrics = flow.textMetrics()
 		cache[comp] = dst
 		cl.starts = append(cl.starts, dst)
 		dst.AddOutput(flow.copyArrow(src.output, cl, true, cache, breakCache))
 		return dst
 	case *EndPort:
 		dst := NewEndPort(src.name)
+		dst.metrics = flow.textMetrics()
 		cache[comp] = dst
 		dst.addInput(flow.copyArrow(src.input, cl, false, cache, breakCache))
 		return dst
@@ -454,6 +500,10 @@ func (flow *Flow) copyArrow(
 		return dst.(*Arrow)
 	}
 	dst := NewArrow(arr.srcPort, arr.dstPort)
+	dst.notes = arr.notes
+	dst.isError = arr.isErrorPath()
+	dst.change = arr.change
+	dst.metrics = flow.textMetrics()
 	for _, dt := range arr.dataTypes {
 		dst.AddDataType(dt.name, dt.typ, dt.link)
 	}
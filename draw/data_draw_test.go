@@ -7,7 +7,7 @@ func buildBigTestFlowData() *draw.Flow {
 }
 
 func buildBigTestFlowData5() *draw.Flow {
-	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, false)
+	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, nil)
 	flow.AddStart(
 		draw.NewStartPort("in").AddOutput(
 			draw.NewArrow("", "").AddDataType(
@@ -123,7 +123,7 @@ func buildBigTestFlowData5() *draw.Flow {
 }
 
 func buildBigTestFlowData4() *draw.Flow {
-	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, false)
+	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, nil)
 	flow.AddStart(
 		draw.NewStartPort("in").AddOutput(
 			draw.NewArrow("", "").AddDataType(
@@ -215,7 +215,7 @@ func buildBigTestFlowData4() *draw.Flow {
 }
 
 func buildBigTestFlowData3() *draw.Flow {
-	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, false)
+	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, nil)
 	flow.AddStart(
 		draw.NewStartPort("in").AddOutput(
 			draw.NewArrow("", "").AddDataType(
@@ -284,7 +284,7 @@ func buildBigTestFlowData3() *draw.Flow {
 }
 
 func buildBigTestFlowData2() *draw.Flow {
-	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, false)
+	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, nil)
 	flow.AddStart(
 		draw.NewStartPort("in3").AddOutput(
 			draw.NewArrow("", "").AddDataType(
@@ -312,7 +312,7 @@ func buildBigTestFlowData2() *draw.Flow {
 }
 
 func buildBigTestFlowData1() *draw.Flow {
-	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, false)
+	flow := draw.NewFlow("bigTestFlow", draw.FlowModeNoLinks, 1500, nil)
 	flow.AddStart(
 		draw.NewComp("Xa", "MiSo", "https://google.com?q=Data", flow).AddOutput( // 1. split
 			draw.NewArrow("special", "in").AddDataType(
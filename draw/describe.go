@@ -0,0 +1,22 @@
+package draw
+
+// portDescription describes a port of the flow in the MarkDown file.
+type portDescription struct {
+	Name        string
+	Description string
+}
+
+// SetDescription sets the text that explains the flow.
+// It is put above the diagram in the MarkDown file.
+func (flow *Flow) SetDescription(text string) *Flow {
+	flow.description = text
+	return flow
+}
+
+// AddPortDescription adds a text that explains a port of the flow.
+// All ports with a description are listed below the diagram in the
+// MarkDown file in the order they have been added.
+func (flow *Flow) AddPortDescription(port, text string) *Flow {
+	flow.portDescriptions = append(flow.portDescriptions, portDescription{Name: port, Description: text})
+	return flow
+}
@@ -0,0 +1,21 @@
+package draw_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDrawDescription(t *testing.T) {
+	flow := buildSmallTestFlow().SetDescription("Parses and merges data.").
+		AddPortDescription("out", "the merged AST").
+		AddPortDescription("error", "the parse error")
+	_, md, err := flow.Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Parses and merges data.\n\n![smallTestFlow](flowdev/flow-smallTestFlow.svg)\n\n" +
+		"#### Ports\n\n- `out`: the merged AST\n- `error`: the parse error\n"
+	if !strings.HasPrefix(string(md), expected) {
+		t.Errorf("expected MarkDown to start with:\n%s\ngot:\n%s", expected, md)
+	}
+}
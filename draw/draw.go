@@ -3,79 +3,75 @@ package draw
 import (
 	"bytes"
 	"fmt"
-	"path/filepath"
+	"strings"
 	"text/template"
 )
 
-type svgColors struct {
-	Background string
-	Text       string
-	Link       string
-	GoLink     string
-	Comp       string
-	Plugin     string
-	PluginType string
-}
-
-var lightColors = svgColors{
-	Background: "rgb(255,255,255)",
-	Text:       "rgb(0,0,0)",
-	Link:       "rgb(32,48,128)",
-	GoLink:     "rgb(0,96,0)",
-	Comp:       "rgb(96,192,255)",
-	Plugin:     "rgb(224,224,32)",
-	PluginType: "rgb(32,224,32)",
-}
-
-var darkColors = svgColors{
-	Background: "rgb(13,17,23)",
-	Text:       "rgb(201,209,217)",
-	Link:       "rgb(96,192,255)",
-	GoLink:     "rgb(32,224,32)",
-	Comp:       "rgb(32,48,128)",
-	Plugin:     "rgb(96,96,0)",
-	PluginType: "rgb(0,96,0)",
-}
-
 const svgDiagram = `<?xml version="1.0" ?>
 <svg version="1.1" xmlns="http://www.w3.org/2000/svg" viewBox="{{.X0}} {{.Y0}} {{.TotalWidth}} {{.TotalHeight}}" width="{{.TotalWidth}}px" height="{{.TotalHeight}}px">
-    <!-- Generated by FlowDev tool. -->
-    <rect fill="{{.Colors.Background}}" fill-opacity="1" width="{{.TotalWidth}}" height="{{.TotalHeight}}" x="{{.X0}}" y="{{.Y0}}"/>
-{{$colors := .Colors}}
+    <!-- Generated by FlowDev tool. -->{{if .Style}}
+    <style><![CDATA[{{cdata .Style}}
+    ]]></style>{{end}}
+    <rect fill="{{xml .Theme.Background}}" fill-opacity="1" width="{{.TotalWidth}}" height="{{.TotalHeight}}" x="{{.X0}}" y="{{.Y0}}"/>
+{{$theme := .Theme}}
+{{- range .Groups}}
+    <rect fill="none" stroke="{{xml $theme.Text}}" stroke-opacity="0.6" stroke-width="{{$theme.ThinStrokeWidth}}" stroke-dasharray="6 3" width="{{.Width}}" height="{{.Height}}" x="{{.X}}" y="{{.Y}}" rx="{{$theme.CornerRadius}}"/>
+    <text fill="{{xml $theme.Text}}" fill-opacity="1.0" font-size="{{$theme.SmallFontSize}}"{{if $theme.FontFamily}} font-family="{{xml $theme.FontFamily}}"{{end}} x="{{.TextX}}" y="{{.TextY}}"{{if not $.Measured}} textLength="{{.TextWidth}}" lengthAdjust="spacingAndGlyphs"{{end}}>{{xml .Label}}</text>
+{{end -}}
 {{- range .Arrows}}
-    <line stroke="{{$colors.Text}}" stroke-opacity="1.0" stroke-width="2" x1="{{.X1}}" y1="{{.Y1}}" x2="{{.X2}}" y2="{{.Y2}}"/>
-    <line stroke="{{$colors.Text}}" stroke-opacity="1.0" stroke-width="2" x1="{{.XTip1}}" y1="{{.YTip1}}" x2="{{.X2}}" y2="{{.Y2}}"/>
-    <line stroke="{{$colors.Text}}" stroke-opacity="1.0" stroke-width="2" x1="{{.XTip2}}" y1="{{.YTip2}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+    <line stroke="{{xml (.Stroke $theme)}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}"{{if .Error}} stroke-dasharray="6 3"{{end}} x1="{{.X1}}" y1="{{.Y1}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+    <line stroke="{{xml (.Stroke $theme)}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" x1="{{.XTip1}}" y1="{{.YTip1}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+    <line stroke="{{xml (.Stroke $theme)}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" x1="{{.XTip2}}" y1="{{.YTip2}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+{{end -}}
+{{- range .Paths}}
+    <polyline fill="none" stroke="{{xml (.Stroke $theme)}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}"{{if .Error}} stroke-dasharray="6 3"{{end}} points="{{range $i, $p := .Points}}{{if $i}} {{end}}{{$p.X}},{{$p.Y}}{{end}}"/>
+    {{- if .Tip}}
+    <line stroke="{{xml (.Stroke $theme)}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" x1="{{.XTip1}}" y1="{{.YTip1}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+    <line stroke="{{xml (.Stroke $theme)}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" x1="{{.XTip2}}" y1="{{.YTip2}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+    {{- end}}
 {{end -}}
 {{- range .Rects}}
 {{- if .SubRect}}
-    <rect fill="{{$colors.PluginType}}" fill-opacity="1.0" stroke="{{$colors.Text}}" stroke-opacity="1.0" stroke-width="1" width="{{.Width}}" height="{{.Height}}" x="{{.X}}" y="{{.Y}}" rx="10"/>
+    <rect fill="{{xml $theme.PluginType}}" fill-opacity="1.0" stroke="{{xml $theme.Text}}" stroke-opacity="1.0" stroke-width="{{$theme.ThinStrokeWidth}}" width="{{.Width}}" height="{{.Height}}" x="{{.X}}" y="{{.Y}}" rx="{{$theme.CornerRadius}}"/>
 {{- else -}}
     {{- if .Plugin}}
-    <rect fill="{{$colors.Plugin}}" fill-opacity="1.0" stroke="{{$colors.Text}}" stroke-opacity="1.0" stroke-width="2" width="{{.Width}}" height="{{.Height}}" x="{{.X}}" y="{{.Y}}" rx="10"/>
+    <rect fill="{{xml $theme.Plugin}}" fill-opacity="1.0" stroke="{{xml $theme.Text}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" width="{{.Width}}" height="{{.Height}}" x="{{.X}}" y="{{.Y}}" rx="{{$theme.CornerRadius}}"/>
+    {{- else if .Shaped}}
+        {{- if .Outline}}
+    <polygon{{with .Classes}} class="{{xml .}}"{{end}} fill="{{xml $theme.Comp}}" fill-opacity="1.0" stroke="{{xml (.Stroke $theme)}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" points="{{range $i, $p := .Outline}}{{if $i}} {{end}}{{$p.X}},{{$p.Y}}{{end}}"{{with .Style}} style="{{xml .}}"{{end}}/>
+        {{- else}}
+    <rect{{with .Classes}} class="{{xml .}}"{{end}} fill="{{xml $theme.Comp}}" fill-opacity="1.0" stroke="{{xml (.Stroke $theme)}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" width="{{.Width}}" height="{{.Height}}" x="{{.X}}" y="{{.Y}}" rx="{{or .Radius $theme.CornerRadius}}"{{with .Style}} style="{{xml .}}"{{end}}/>
+        {{- end}}
+        {{- range .Marks}}
+    <polyline fill="none" stroke="{{xml $theme.Text}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" points="{{range $i, $p := .}}{{if $i}} {{end}}{{$p.X}},{{$p.Y}}{{end}}"/>
+        {{- end}}
     {{- else}}
-    <rect fill="{{$colors.Comp}}" fill-opacity="1.0" stroke="{{$colors.Text}}" stroke-opacity="1.0" stroke-width="2" width="{{.Width}}" height="{{.Height}}" x="{{.X}}" y="{{.Y}}" rx="10"/>
+    <rect fill="{{xml $theme.Comp}}" fill-opacity="1.0" stroke="{{xml $theme.Text}}" stroke-opacity="1.0" stroke-width="{{$theme.StrokeWidth}}" width="{{.Width}}" height="{{.Height}}" x="{{.X}}" y="{{.Y}}" rx="{{$theme.CornerRadius}}"/>
     {{- end}}
 {{- end}}
 {{- end -}}
+{{- range .Markers}}
+    <circle fill="{{xml $theme.Error}}" fill-opacity="1.0" cx="{{.X}}" cy="{{.Y}}" r="{{.Radius}}"/>
+    <text fill="{{xml $theme.Background}}" fill-opacity="1.0" font-size="{{$theme.SmallFontSize}}" font-weight="bold"{{if $theme.FontFamily}} font-family="{{xml $theme.FontFamily}}"{{end}} x="{{.TextX}}" y="{{.TextY}}"{{if not $.Measured}} textLength="{{.TextWidth}}" lengthAdjust="spacingAndGlyphs"{{end}}>!</text>
+{{- end -}}
 {{- if .Texts}}
 {{end -}}
 {{- range .Texts}}
 {{- if .Small}}
     {{- if .GoLink}}
-    <text fill="{{$colors.GoLink}}" fill-opacity="1.0" font-size="14" x="{{.X}}" y="{{.Y}}" textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs">{{.Text}}</text>
+    <text fill="{{xml $theme.GoLink}}" fill-opacity="1.0" font-size="{{$theme.SmallFontSize}}"{{if $theme.FontFamily}} font-family="{{xml $theme.FontFamily}}"{{end}} x="{{.X}}" y="{{.Y}}"{{if not $.Measured}} textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs"{{end}}>{{xml .Text}}</text>
     {{- else if .Link}}
-    <text fill="{{$colors.Link}}" fill-opacity="1.0" font-size="14" x="{{.X}}" y="{{.Y}}" textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs">{{.Text}}</text>
+    <text fill="{{xml $theme.Link}}" fill-opacity="1.0" font-size="{{$theme.SmallFontSize}}"{{if $theme.FontFamily}} font-family="{{xml $theme.FontFamily}}"{{end}} x="{{.X}}" y="{{.Y}}"{{if not $.Measured}} textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs"{{end}}>{{xml .Text}}</text>
     {{- else}}
-    <text fill="{{$colors.Text}}" fill-opacity="1.0" font-size="14" x="{{.X}}" y="{{.Y}}" textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs">{{.Text}}</text>
+    <text fill="{{if .Error}}{{xml $theme.Error}}{{else}}{{xml $theme.Text}}{{end}}" fill-opacity="1.0" font-size="{{$theme.SmallFontSize}}"{{if $theme.FontFamily}} font-family="{{xml $theme.FontFamily}}"{{end}}{{if .Italic}} font-style="italic"{{end}} x="{{.X}}" y="{{.Y}}"{{if not $.Measured}} textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs"{{end}}>{{xml .Text}}</text>
     {{- end}}
 {{- else}}
     {{- if .GoLink}}
-    <text fill="{{$colors.GoLink}}" fill-opacity="1.0" font-size="16" x="{{.X}}" y="{{.Y}}" textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs">{{.Text}}</text>
+    <text fill="{{xml $theme.GoLink}}" fill-opacity="1.0" font-size="{{$theme.FontSize}}"{{if $theme.FontFamily}} font-family="{{xml $theme.FontFamily}}"{{end}} x="{{.X}}" y="{{.Y}}"{{if not $.Measured}} textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs"{{end}}>{{xml .Text}}</text>
     {{- else if .Link}}
-    <text fill="{{$colors.Link}}" fill-opacity="1.0" font-size="16" x="{{.X}}" y="{{.Y}}" textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs">{{.Text}}</text>
+    <text fill="{{xml $theme.Link}}" fill-opacity="1.0" font-size="{{$theme.FontSize}}"{{if $theme.FontFamily}} font-family="{{xml $theme.FontFamily}}"{{end}} x="{{.X}}" y="{{.Y}}"{{if not $.Measured}} textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs"{{end}}>{{xml .Text}}</text>
     {{- else}}
-    <text fill="{{$colors.Text}}" fill-opacity="1.0" font-size="16" x="{{.X}}" y="{{.Y}}" textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs">{{.Text}}</text>
+    <text fill="{{if .Error}}{{xml $theme.Error}}{{else}}{{xml $theme.Text}}{{end}}" fill-opacity="1.0" font-size="{{$theme.FontSize}}"{{if $theme.FontFamily}} font-family="{{xml $theme.FontFamily}}"{{end}} x="{{.X}}" y="{{.Y}}"{{if not $.Measured}} textLength="{{.Width}}" lengthAdjust="spacingAndGlyphs"{{end}}>{{xml .Text}}</text>
     {{- end}}
 {{- end}}
 {{- end -}}
@@ -84,14 +80,34 @@ const svgDiagram = `<?xml version="1.0" ?>
 </svg>
 `
 
-var svgTmpl = template.Must(template.New("svgDiagram").Parse(svgDiagram))
+// svgTmpl escapes all texts and attributes that can come from users
+// (e.g. notes, group labels and theme colors) with the template function
+// xml. The CSS of the style is kept in a CDATA section.
+var svgTmpl = template.Must(template.New("svgDiagram").
+	Funcs(template.FuncMap{"xml": template.HTMLEscapeString, "cdata": cdataText}).Parse(svgDiagram))
+
+// cdataText splits every "]]>" in text, so it can't end the CDATA section.
+func cdataText(text string) string {
+	return strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>")
+}
 
 const mdDiagram = `
+{{- if .Description}}{{.Description}}
+
+{{end}}
 {{- if .FlowLines}}
 {{- $maxLine := .MaxLine -}}
 {{range $i, $flowLine := .FlowLines}}
     {{- range $cell := $flowLine -}}
-        {{- if $cell.Link -}}
+        {{- if $cell.Sources -}}
+            {{- if $cell.Link}}<a href="{{$cell.Link}}">{{end -}}
+            <picture>{{range $cell.Sources}}<source media="{{.Media}}" srcset="{{.SVG}}">{{end}}<img alt="{{$cell.Name}}" src="{{$cell.SVG}}"></picture>
+            {{- if $cell.Link}}</a>{{end -}}
+        {{- else if $cell.DarkSVG -}}
+            {{- if $cell.Link}}<a href="{{$cell.Link}}">{{end -}}
+            <picture><source media="(prefers-color-scheme: dark)" srcset="{{$cell.DarkSVG}}"><img alt="{{$cell.Name}}" src="{{$cell.SVG}}"></picture>
+            {{- if $cell.Link}}</a>{{end -}}
+        {{- else if $cell.Link -}}
             [![{{$cell.Name}}]({{$cell.SVG}})]({{$cell.Link}})
         {{- else -}}
             ![{{$cell.Name}}]({{$cell.SVG}})
@@ -102,6 +118,12 @@ const mdDiagram = `
 {{- else}}
 ![{{.Flow.Name}}]({{.Flow.SVG}})
 {{- end}}
+{{- if .Ports}}
+#### Ports
+{{range .Ports}}
+- ` + "`{{.Name}}`" + `: {{.Description}}
+{{- end}}
+{{end}}
 {{- if .DataTypes}}
 
 #### Data Types
@@ -110,7 +132,7 @@ const mdDiagram = `
 {{- if .Subflows}}
 
 #### Subflows
-{{range $name, $link := .Flows}}[{{$name}}]({{$link}}), {{end}}
+{{range $name, $link := .Subflows}}[{{$name}}]({{$link}}), {{end}}
 {{end}}
 {{- if .GoFuncs}}
 
@@ -131,6 +153,23 @@ type svgArrow struct {
 	X2, Y2       int
 	XTip1, YTip1 int
 	XTip2, YTip2 int
+	Error        bool
+	Change       Change
+}
+
+// svgPath is a line with bends and an optional tip at its end (X2, Y2).
+type svgPath struct {
+	Points       []svgPoint
+	Tip          bool
+	X2, Y2       int
+	XTip1, YTip1 int
+	XTip2, YTip2 int
+	Error        bool
+	Change       Change
+}
+
+type svgPoint struct {
+	X, Y int
 }
 
 type svgRect struct {
@@ -139,6 +178,10 @@ type svgRect struct {
 	Width   int
 	Plugin  bool
 	SubRect bool
+	Kind    CompKind // only for the outer shape of a component
+	Class   string
+	Style   string
+	Change  Change // only for the outer shape of a component
 }
 
 type svgText struct {
@@ -146,18 +189,50 @@ type svgText struct {
 	Width  int
 	Text   string
 	Small  bool
+	Italic bool
 	Link   bool
 	GoLink bool
+	Error  bool
+}
+
+// svgMarker is the error marker of a component with hidden error paths.
+type svgMarker struct {
+	X, Y   int // center
+	Radius int
+}
+
+// TextX returns the x value of the exclamation mark.
+func (m *svgMarker) TextX() int { return m.X - m.TextWidth()/2 }
+
+// TextY returns the y value of the exclamation mark.
+func (m *svgMarker) TextY() int { return m.Y + m.Radius - TextOffset/2 }
+
+// TextWidth returns the width of the exclamation mark.
+func (m *svgMarker) TextWidth() int { return m.Radius / 2 }
+
+// svgGroup is the box of a group with its label above it.
+type svgGroup struct {
+	X, Y         int
+	Width        int
+	Height       int
+	Label        string
+	TextX, TextY int
+	TextWidth    int
 }
 
 type svgFlow struct {
 	X0, Y0      int
 	TotalHeight int
 	TotalWidth  int
+	Groups      []*svgGroup
 	Arrows      []*svgArrow
+	Paths       []*svgPath
 	Rects       []*svgRect
+	Markers     []*svgMarker
 	Texts       []*svgText
-	Colors      svgColors
+	Theme       *Theme
+	Style       string
+	Measured    bool // texts are measured, so they don't need to be squeezed
 }
 
 func newSVGFlow(x0, y0, height, width, size int) *svgFlow {
@@ -173,18 +248,27 @@ func newSVGFlow(x0, y0, height, width, size int) *svgFlow {
 }
 
 type svgLink struct {
-	Name string
-	SVG  string
-	Link string
+	Name    string
+	SVG     string
+	DarkSVG string
+	Link    string
+	Sources []svgSource // alternatives chosen by media query
+}
+
+type svgSource struct {
+	Media string
+	SVG   string
 }
 
 type mdFlow struct {
-	Flow      svgLink
-	FlowLines [][]*svgLink
-	MaxLine   int
-	DataTypes map[string]string
-	Subflows  map[string]string
-	GoFuncs   map[string]string
+	Description string
+	Ports       []portDescription
+	Flow        svgLink
+	FlowLines   [][]*svgLink
+	MaxLine     int
+	DataTypes   map[string]string
+	Subflows    map[string]string
+	GoFuncs     map[string]string
 }
 
 func newMDFlow() *mdFlow {
@@ -197,24 +281,26 @@ func newMDFlow() *mdFlow {
 }
 
 type svgMDFlow struct {
-	svgs          map[string]*svgFlow
-	md            *mdFlow
-	svgFilePrefix string
-	lastX         int
+	svgs     map[string]*svgFlow
+	md       *mdFlow
+	naming   Naming
+	flowName string
+	lastX    int
 }
 
 func flowToSVGs(f *Flow) *svgMDFlow {
 	smf := &svgMDFlow{
-		svgs:          make(map[string]*svgFlow, 256),
-		md:            newMDFlow(),
-		svgFilePrefix: filepath.Join(".", "flowdev", "flow-"+f.name),
+		svgs:     make(map[string]*svgFlow, 256),
+		md:       newMDFlow(),
+		naming:   f.namingOrDefault(),
+		flowName: f.name,
 	}
 	fd := f.getDrawData()
 
 	if f.mode != FlowModeMDLinks {
 		smf.md.Flow = svgLink{
 			Name: f.name,
-			SVG:  smf.svgFilePrefix + ".svg",
+			SVG:  smf.naming.Diagram(f.name),
 		}
 		svg := newSVGFlow(0, 0, fd.height, fd.width+1, bigDiagramSize)
 		smf.svgs[""] = svg
@@ -236,24 +322,27 @@ func flowToSVGs(f *Flow) *svgMDFlow {
 	return smf
 }
 
-func svgFlowsToBytes(sfs map[string]*svgFlow, dark bool) (map[string][]byte, error) {
+func svgFlowsToBytes(sfs map[string]*svgFlow, theme *Theme, format Format) (map[string][]byte, error) {
 	sfbs := make(map[string][]byte)
 	for key, sf := range sfs {
-		bs, err := svgFlowToBytes(sf, dark)
+		bs, err := svgFlowToFormat(sf, theme, format)
 		if err != nil {
-			return nil, fmt.Errorf("unable to create SVG file %q: %w", key, err)
+			return nil, fmt.Errorf("unable to create diagram file %q: %w", key, err)
 		}
 		sfbs[key] = bs
 	}
 	return sfbs, nil
 }
 
-func svgFlowToBytes(sf *svgFlow, dark bool) ([]byte, error) {
+func svgFlowToBytes(sf *svgFlow, theme *Theme) ([]byte, error) {
 	buf := bytes.Buffer{}
-	if dark {
-		sf.Colors = darkColors
-	} else {
-		sf.Colors = lightColors
+	sf.Theme = theme
+	sf.Style = ""
+	if theme.Dark != nil {
+		sf.Theme, sf.Style = cssTheme(theme)
+	}
+	if theme.CSS != "" {
+		sf.Style += "\n        " + theme.CSS
 	}
 	err := svgTmpl.Execute(&buf, sf)
 	if err != nil {
@@ -262,6 +351,28 @@ func svgFlowToBytes(sf *svgFlow, dark bool) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// picturesToBytes creates a light and a dark file for each SVG flow and
+// links the dark files in the MarkDown.
+func picturesToBytes(smf *svgMDFlow, theme *Theme, format Format) (map[string][]byte, error) {
+	sfbs, err := svgFlowsToBytes(smf.svgs, theme.lightOnly(), format)
+	if err != nil {
+		return nil, err
+	}
+	darkSFBs, err := svgFlowsToBytes(smf.svgs, theme.darkOnly(), format)
+	if err != nil {
+		return nil, err
+	}
+	for name, bs := range darkSFBs {
+		sfbs[darkName(name)] = bs
+	}
+	for _, flowLine := range smf.md.FlowLines {
+		for _, cell := range flowLine {
+			cell.DarkSVG = darkName(cell.SVG)
+		}
+	}
+	return sfbs, nil
+}
+
 func mdFlowToBytes(mdf *mdFlow) ([]byte, error) {
 	buf := bytes.Buffer{}
 	mdf.MaxLine = len(mdf.FlowLines) - 1
@@ -292,7 +403,7 @@ func addNewSVGFlow(
 
 func addFillerSVG(smf *svgMDFlow, line, x, height, width int) {
 	svg := newSVGFlow(0, 0, height, width, tinyDiagramSize)
-	name := fmt.Sprintf("%s-filler-%d-%d.svg", smf.svgFilePrefix, width, height)
+	name := smf.naming.Filler(smf.flowName, width, height)
 
 	smf.svgs[name] = svg
 	addSVGLinkToMDFlowLines(smf, line, name, "filler")
@@ -314,13 +425,14 @@ func addSVGLinkToMDFlowLines(smf *svgMDFlow, line int, svgName, desc string) *sv
 	return sl
 }
 
+// svgFileName returns the name of the tile for the component.
+// Slashes in the component name (e.g. of a package path) are replaced, so
+// the tile stays in the directory of the other tiles.
 func svgFileName(smf *svgMDFlow, compName string, line int) string {
 	idx := 0
 	if len(smf.md.FlowLines) > line {
 		idx = len(smf.md.FlowLines[line])
 	}
-	if compName == "" {
-		return fmt.Sprintf("%s-%d-%d.svg", smf.svgFilePrefix, idx, line)
-	}
-	return fmt.Sprintf("%s-%d-%d-%s.svg", smf.svgFilePrefix, idx, line, compName)
+	compName = strings.NewReplacer("/", "_", "\\", "_").Replace(compName)
+	return smf.naming.Tile(smf.flowName, idx, line, compName)
 }
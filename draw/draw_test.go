@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/flowdev/ea-flow-doc/draw"
@@ -15,6 +16,7 @@ func TestFromFlowData(t *testing.T) {
 		Dir: "testdata",
 		Cmds: map[string]func(*testscript.TestScript, bool, []string){
 			"drawBigTestFlowData": drawBigTestFlowData,
+			"drawFlowFile":        drawFlowFile,
 		},
 		// TestWork: true,
 	})
@@ -23,8 +25,8 @@ func TestFromFlowData(t *testing.T) {
 func drawBigTestFlowData(ts *testscript.TestScript, _ bool, args []string) {
 	workDir := ts.Getenv("WORK")
 
-	if len(args) != 3 {
-		ts.Fatalf("expected 3 args (splitMode, darkMode and width), got: %q", args)
+	if len(args) != 3 && len(args) != 4 {
+		ts.Fatalf("expected 3 or 4 args (splitMode, darkMode, width and optional direction), got: %q", args)
 	}
 	splitMode, err := strconv.ParseBool(args[0])
 	if err != nil {
@@ -38,14 +40,27 @@ func drawBigTestFlowData(ts *testscript.TestScript, _ bool, args []string) {
 	if err != nil {
 		ts.Fatalf("expected unsigned int for width, got: %q; err: %v", args[2], err)
 	}
+	direction := draw.LayoutLeftToRight
 	mdFile := "markdown-" + args[0] + "-" + args[1] + "-" + args[2] + ".actual"
+	if len(args) == 4 {
+		if args[3] != "topDown" {
+			ts.Fatalf("expected 'topDown' as direction, got: %q", args[3])
+		}
+		direction = draw.LayoutTopDown
+		mdFile = "markdown-" + args[0] + "-" + args[1] + "-" + args[2] + "-" + args[3] + ".actual"
+	}
 
 	flowMode := draw.FlowModeNoLinks
 	if splitMode {
 		flowMode = draw.FlowModeMDLinks
 	}
+	theme := draw.LightTheme()
+	if darkMode {
+		theme = draw.DarkTheme()
+	}
 	bigTestFlowData := buildBigTestFlowData()
-	bigTestFlowData.ChangeConfig("bigTestFlow"+args[2], flowMode, int(width), darkMode)
+	bigTestFlowData.ChangeConfig("bigTestFlow"+strings.Join(args[2:], ""), flowMode, int(width), theme)
+	bigTestFlowData.SetDirection(direction)
 	svgContents, mdContent, err := bigTestFlowData.Draw()
 	if err != nil {
 		ts.Fatalf("unexpected error: %s", err)
@@ -72,3 +87,38 @@ func drawBigTestFlowData(ts *testscript.TestScript, _ bool, args []string) {
 		ts.Fatalf("unable to write file %q: %v", mdFile+".md", err)
 	}
 }
+
+// drawFlowFile draws the flow of a flow file with an optional theme file
+// and writes the diagrams and the MarkDown file (<name>.md) to the work
+// directory.
+func drawFlowFile(ts *testscript.TestScript, _ bool, args []string) {
+	if len(args) != 1 && len(args) != 2 {
+		ts.Fatalf("expected a flow file and an optional theme file, got: %q", args)
+	}
+	flow, err := draw.FlowFromJSON([]byte(ts.ReadFile(args[0])))
+	if err != nil {
+		ts.Fatalf("unable to read flow file %q: %v", args[0], err)
+	}
+	if len(args) == 2 {
+		theme, err := draw.ThemeFromJSON([]byte(ts.ReadFile(args[1])))
+		if err != nil {
+			ts.Fatalf("unable to read theme file %q: %v", args[1], err)
+		}
+		flow.SetTheme(theme)
+	}
+	svgContents, mdContent, err := flow.Draw()
+	if err != nil {
+		ts.Fatalf("unexpected error: %s", err)
+	}
+
+	svgContents[flow.Name()+".md"] = mdContent
+	for fnam, fcontent := range svgContents {
+		workFNam := ts.MkAbs(fnam)
+		if err = os.MkdirAll(filepath.Dir(workFNam), 0777); err != nil {
+			ts.Fatalf("unable to create directory for file %q: %v", workFNam, err)
+		}
+		if err = os.WriteFile(workFNam, fcontent, 0666); err != nil {
+			ts.Fatalf("unable to write file %q: %v", workFNam, err)
+		}
+	}
+}
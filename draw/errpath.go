@@ -0,0 +1,98 @@
+package draw
+
+// ErrorPort is the name of the port that error paths start at or end in.
+const ErrorPort = "error"
+
+const markerRadius = 7 // radius of the error marker of a component
+
+// MarkError marks the arrow as part of an error path.
+// Arrows from or to ErrorPort and arrows into the end port ErrorPort are
+// error arrows anyway.
+// Error arrows are drawn dashed in the error color of the theme.
+func (arr *Arrow) MarkError() *Arrow {
+	arr.isError = true
+	return arr
+}
+
+// isErrorPath tells if the arrow is part of an error path.
+func (arr *Arrow) isErrorPath() bool {
+	if arr.isError || arr.srcPort == ErrorPort || arr.dstPort == ErrorPort {
+		return true
+	}
+	end, ok := arr.dstComp.(*EndPort)
+	return ok && end.name == ErrorPort
+}
+
+// UseHappyPath hides all error paths of the flow.
+// Error arrows and everything that can only be reached through them
+// aren't drawn. Instead the components they start at get a single error
+// marker.
+func (flow *Flow) UseHappyPath() *Flow {
+	flow.happyPath = true
+	return flow
+}
+
+// happyComps returns all shapes that can be reached from the starts without
+// following error arrows.
+func happyComps(starts []StartComp) map[anyComp]bool {
+	kept := make(map[anyComp]bool, 128)
+	var visit func(comp anyComp)
+	visit = func(comp anyComp) {
+		if comp == nil || kept[comp] {
+			return
+		}
+		kept[comp] = true
+		for _, out := range outputsOf(comp) {
+			if out != nil && !out.isErrorPath() {
+				visit(out.dstComp)
+			}
+		}
+	}
+	for _, start := range starts {
+		visit(start)
+	}
+	return kept
+}
+
+// keptArrows returns the arrows that are drawn.
+// All arrows are drawn unless the happy path is drawn.
+func (flow *Flow) keptArrows(arrs []*Arrow) []*Arrow {
+	if flow.happyComps == nil {
+		return arrs
+	}
+	kept := make([]*Arrow, 0, len(arrs))
+	for _, arr := range arrs {
+		if !arr.isErrorPath() && flow.happyComps[arr.srcComp] && flow.happyComps[arr.dstComp] {
+			kept = append(kept, arr)
+		}
+	}
+	return kept
+}
+
+// hasHiddenErrors tells if error arrows of the component aren't drawn.
+func (flow *Flow) hasHiddenErrors(comp *Comp) bool {
+	if flow.happyComps == nil {
+		return false
+	}
+	for _, out := range comp.outputs {
+		if out.isErrorPath() {
+			return true
+		}
+	}
+	return false
+}
+
+// errorMarkerToSVG adds the error marker in the upper right corner of the
+// component.
+func (comp *Comp) errorMarkerToSVG(svg *svgFlow) {
+	if !comp.errorMarker {
+		return
+	}
+	cd := comp.drawData
+	_, right := kindPadding(comp.kind)
+	svg.Markers = append(svg.Markers, &svgMarker{
+		X:      cd.x0 + comp.calcMainWidth() - right - WordGap/2 - markerRadius,
+		Y:      cd.y0 + LineHeight/2,
+		Radius: markerRadius,
+	})
+}
@@ -0,0 +1,39 @@
+package draw_test
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestDrawErrorPathsPNG(t *testing.T) {
+	background := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	errorColor := color.RGBA{R: 192, A: 255}
+
+	pi := drawPNGImage(t, archiveFlow(t, "errpath.txt", "errors.json"), 0, 0, 307, 112)
+	pi.checkColor(t, "dash of error arrow", 143, 40, errorColor)
+	pi.checkColor(t, "gap of error arrow", 147.5, 40, background)
+
+	pi = drawPNGImage(t, archiveFlow(t, "errpath.txt", "happy.json"), 0, 0, 211, 48)
+	pi.checkColor(t, "error marker", 81, 12, errorColor)
+	pi.checkColor(t, "error marker", 164, 17, errorColor)
+}
+
+func TestDrawErrorPathsPDF(t *testing.T) {
+	pdf := drawFile(t, archiveFlow(t, "errpath.txt", "errors.json"), draw.FormatPDF)
+	if expected := "\nq 0.753 0 0 RG [6 3] 0 d\n140 40 m 204 40 l S\n[] 0 d\n"; !bytes.Contains(pdf, []byte(expected)) {
+		t.Errorf("expected PDF to contain %q, got:\n%s", expected, pdf)
+	}
+
+	pdf = drawFile(t, archiveFlow(t, "errpath.txt", "happy.json"), draw.FormatPDF)
+	if bytes.Contains(pdf, []byte("[6 3] 0 d")) {
+		t.Errorf("expected happy path without dashed arrows, got:\n%s", pdf)
+	}
+	for _, expected := range []string{"\n0.753 0 0 rg\n86 5 m\n", "\n0.753 0 0 rg\n164 5 m\n", "(!) Tj"} {
+		if !bytes.Contains(pdf, []byte(expected)) {
+			t.Errorf("expected PDF to contain %q, got:\n%s", expected, pdf)
+		}
+	}
+}
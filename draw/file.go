@@ -0,0 +1,378 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A flow file describes a flow declaratively in JSON.
+// It contains the same information as a flow built with NewFlow, NewComp,
+// NewArrow, ... in Go:
+//
+//	{
+//	  "name": "myFlow", "mode": "noLinks", "width": 1500, "dark": false,
+//	  "metrics": "fixed", "direction": "leftToRight", "layered": false, "routing": false,
+//	  "happyPath": false,
+//	  "starts": [
+//	    {"startPort": "in", "output": {
+//	      "dataTypes": [{"name": "data", "type": "Data", "link": "..."}],
+//	      "to": {"comp": {"name": "x", "type": "X", "link": "...", "goLink": false,
+//	        "kind": "func", "class": "pure", "style": "fill: orange", "change": "added",
+//	        "notes": ["retries 3 times"],
+//	        "plugins": [{"title": "semantics", "plugins": [{"type": "T", "link": "..."}]}],
+//	        "outputs": [
+//	          {"srcPort": "out", "dstPort": "in", "notes": ["sorted"], "to": {"endPort": "out"}},
+//	          {"srcPort": "failed", "error": true, "change": "removed", "linkComp": "x"},
+//	          {"to": {"loop": {"name": "x", "port": "in", "link": "..."}}}
+//	        ]
+//	      }}
+//	    }}
+//	  ],
+//	  "groups": [{"label": "billing", "comps": ["x"]}]
+//	}
+//
+// Every node contains exactly one of: startPort, comp, endPort or loop.
+// Every arrow contains either a destination node ("to") or the ID of a
+// component to link to ("linkComp"). Linked components can be defined
+// anywhere in the file.
+// The mode is either "noLinks" (default) or "mdLinks".
+// The metrics are either "font" (default, see FontMetrics) or "fixed".
+// The direction is either "leftToRight" (default) or "topDown".
+// The kind of a component is one of "func" (default), "flow", "database",
+// "service", "queue" or "decision" (see CompKind).
+// With "layered" the flow is laid out with as few crossing arrows as possible.
+// With "routing" loops and breaks are drawn as connections where possible.
+// Arrows with "error" are drawn as error paths (see Arrow.MarkError).
+// Components and arrows with a "change" of "added" or "removed" are drawn
+// in the color of the change (see Change).
+// With "happyPath" the error paths are hidden (see Flow.UseHappyPath).
+// Notes are lines of free text at components and arrows. Groups draw a
+// labeled box around the components with the given IDs.
+type fileFlow struct {
+	Name      string       `json:"name"`
+	Mode      string       `json:"mode"`
+	Width     int          `json:"width"`
+	Dark      bool         `json:"dark"`
+	Metrics   string       `json:"metrics"`
+	Direction string       `json:"direction"`
+	Layered   bool         `json:"layered"`
+	Routing   bool         `json:"routing"`
+	HappyPath bool         `json:"happyPath"`
+	Starts    []*fileNode  `json:"starts"`
+	Groups    []*fileGroup `json:"groups"`
+}
+
+type fileGroup struct {
+	Label string   `json:"label"`
+	Comps []string `json:"comps"`
+}
+
+type fileNode struct {
+	StartPort *string    `json:"startPort"`
+	Output    *fileArrow `json:"output"`
+	Comp      *fileComp  `json:"comp"`
+	EndPort   *string    `json:"endPort"`
+	Loop      *fileLoop  `json:"loop"`
+}
+
+type fileComp struct {
+	Name    string             `json:"name"`
+	Type    string             `json:"type"`
+	Link    string             `json:"link"`
+	GoLink  bool               `json:"goLink"`
+	Kind    string             `json:"kind"`
+	Class   string             `json:"class"`
+	Style   string             `json:"style"`
+	Notes   []string           `json:"notes"`
+	Change  string             `json:"change"`
+	Plugins []*filePluginGroup `json:"plugins"`
+	Outputs []*fileArrow       `json:"outputs"`
+}
+
+type filePluginGroup struct {
+	Title   string        `json:"title"`
+	Plugins []*filePlugin `json:"plugins"`
+}
+
+type filePlugin struct {
+	Type   string `json:"type"`
+	Link   string `json:"link"`
+	GoLink bool   `json:"goLink"`
+}
+
+type fileLoop struct {
+	Name   string `json:"name"`
+	Port   string `json:"port"`
+	Link   string `json:"link"`
+	GoLink bool   `json:"goLink"`
+}
+
+type fileArrow struct {
+	SrcPort   string          `json:"srcPort"`
+	DstPort   string          `json:"dstPort"`
+	DataTypes []*fileDataType `json:"dataTypes"`
+	Notes     []string        `json:"notes"`
+	Error     bool            `json:"error"`
+	Change    string          `json:"change"`
+	To        *fileNode       `json:"to"`
+	LinkComp  string          `json:"linkComp"`
+}
+
+type fileDataType struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Link string `json:"link"`
+}
+
+// pendingLink is an arrow that has to be linked to a component after all
+// components have been created.
+type pendingLink struct {
+	arr  *Arrow
+	id   string
+	path string
+}
+
+// ReadFlowFile reads a flow from the JSON file with the given name.
+func ReadFlowFile(name string) (*Flow, error) {
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read flow file: %w", err)
+	}
+	flow, err := FlowFromJSON(bs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read flow file %q: %w", name, err)
+	}
+	return flow, nil
+}
+
+// FlowFromJSON creates a flow from its JSON description.
+// The format is documented at the fileFlow type in the source code.
+func FlowFromJSON(bs []byte) (*Flow, error) {
+	ff := &fileFlow{}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(ff); err != nil {
+		return nil, fmt.Errorf("unable to decode JSON: %w", err)
+	}
+
+	mode := FlowModeNoLinks
+	switch ff.Mode {
+	case "", "noLinks":
+	case "mdLinks":
+		mode = FlowModeMDLinks
+	default:
+		return nil, fmt.Errorf("unknown flow mode %q (expected 'noLinks' or 'mdLinks')", ff.Mode)
+	}
+	if ff.Name == "" {
+		return nil, fmt.Errorf("missing name of the flow")
+	}
+	if strings.ContainsAny(ff.Name, `/\`) || strings.Contains(ff.Name, "..") {
+		return nil, fmt.Errorf("name of the flow %q can't contain path separators or '..'", ff.Name)
+	}
+
+	theme := LightTheme()
+	if ff.Dark {
+		theme = DarkTheme()
+	}
+	flow := NewFlow(ff.Name, mode, ff.Width, theme)
+	switch ff.Metrics {
+	case "", "font":
+		flow.SetTextMetrics(FontMetrics{})
+	case "fixed":
+	default:
+		return nil, fmt.Errorf("unknown text metrics %q (expected 'fixed' or 'font')", ff.Metrics)
+	}
+	switch ff.Direction {
+	case "", "leftToRight":
+	case "topDown":
+		flow.SetDirection(LayoutTopDown)
+	default:
+		return nil, fmt.Errorf("unknown layout direction %q (expected 'leftToRight' or 'topDown')", ff.Direction)
+	}
+	if ff.Layered {
+		flow.UseLayeredLayout()
+	}
+	if ff.Routing {
+		flow.UseRouting()
+	}
+	if ff.HappyPath {
+		flow.UseHappyPath()
+	}
+	links := make([]pendingLink, 0, 32)
+	for i, fn := range ff.Starts {
+		path := "starts[" + strconv.Itoa(i) + "]"
+		start, err := flow.startFromFile(fn, path, &links)
+		if err != nil {
+			return nil, err
+		}
+		flow.AddStart(start)
+	}
+	for _, pl := range links {
+		if err := pl.arr.LinkComp(pl.id, flow); err != nil {
+			return nil, fmt.Errorf("%s: %w", pl.path, err)
+		}
+	}
+	for i, fg := range ff.Groups {
+		g := NewGroup(fg.Label)
+		for j, id := range fg.Comps {
+			comp := flow.lookup(id)
+			if comp == nil {
+				return nil, fmt.Errorf("groups[%d].comps[%d]: unknown component with ID: %q", i, j, id)
+			}
+			g.AddComp(comp)
+		}
+		flow.AddGroup(g)
+	}
+	return flow, nil
+}
+
+func (flow *Flow) startFromFile(fn *fileNode, path string, links *[]pendingLink) (StartComp, error) {
+	if err := checkFileNode(fn, path); err != nil {
+		return nil, err
+	}
+	switch {
+	case fn.StartPort != nil:
+		if fn.Output == nil {
+			return nil, fmt.Errorf("%s: start port %q needs an output", path, *fn.StartPort)
+		}
+		arr, err := flow.arrowFromFile(fn.Output, path+".output", links)
+		if err != nil {
+			return nil, err
+		}
+		return NewStartPort(*fn.StartPort).AddOutput(arr), nil
+	case fn.Comp != nil:
+		return flow.compFromFile(fn.Comp, path+".comp", links)
+	default:
+		return nil, fmt.Errorf("%s: a flow can only start with a start port or a component", path)
+	}
+}
+
+func (flow *Flow) endFromFile(fn *fileNode, path string, links *[]pendingLink) (EndComp, error) {
+	if err := checkFileNode(fn, path); err != nil {
+		return nil, err
+	}
+	switch {
+	case fn.Comp != nil:
+		return flow.compFromFile(fn.Comp, path+".comp", links)
+	case fn.EndPort != nil:
+		return NewEndPort(*fn.EndPort), nil
+	case fn.Loop != nil:
+		loop := NewLoop(fn.Loop.Name, fn.Loop.Port, fn.Loop.Link)
+		if fn.Loop.GoLink {
+			loop.GoLink()
+		}
+		return loop, nil
+	default:
+		return nil, fmt.Errorf("%s: an arrow can only point to a component, an end port or a loop", path)
+	}
+}
+
+func checkFileNode(fn *fileNode, path string) error {
+	if fn == nil {
+		return fmt.Errorf("%s: missing node", path)
+	}
+	n := 0
+	if fn.StartPort != nil {
+		n++
+	}
+	if fn.Comp != nil {
+		n++
+	}
+	if fn.EndPort != nil {
+		n++
+	}
+	if fn.Loop != nil {
+		n++
+	}
+	if n != 1 {
+		return fmt.Errorf("%s: a node must be exactly one of: startPort, comp, endPort or loop; found %d", path, n)
+	}
+	if fn.Output != nil && fn.StartPort == nil {
+		return fmt.Errorf("%s: only start ports can have an output", path)
+	}
+	return nil
+}
+
+func (flow *Flow) compFromFile(fc *fileComp, path string, links *[]pendingLink) (*Comp, error) {
+	if fc.Name == "" && fc.Type == "" {
+		return nil, fmt.Errorf("%s: a component needs a name or a type", path)
+	}
+	id := fc.Name
+	if id == "" {
+		id = fc.Type
+	}
+	if flow.lookup(id) != nil {
+		return nil, fmt.Errorf("%s: duplicate component with ID: %q", path, id)
+	}
+	kind, err := ParseCompKind(fc.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	change, err := ParseChange(fc.Change)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	comp := NewComp(fc.Name, fc.Type, fc.Link, flow).SetKind(kind).SetClass(fc.Class).SetStyle(fc.Style).MarkChange(change)
+	if fc.GoLink {
+		comp.GoLink()
+	}
+	for _, note := range fc.Notes {
+		comp.AddNote(note)
+	}
+	for _, fpg := range fc.Plugins {
+		pg := NewPluginGroup(fpg.Title)
+		for _, fp := range fpg.Plugins {
+			p := NewPlugin(fp.Type, fp.Link)
+			if fp.GoLink {
+				p.GoLink()
+			}
+			pg.AddPlugin(p)
+		}
+		comp.AddPluginGroup(pg)
+	}
+	for i, fa := range fc.Outputs {
+		arr, err := flow.arrowFromFile(fa, path+".outputs["+strconv.Itoa(i)+"]", links)
+		if err != nil {
+			return nil, err
+		}
+		comp.AddOutput(arr)
+	}
+	return comp, nil
+}
+
+func (flow *Flow) arrowFromFile(fa *fileArrow, path string, links *[]pendingLink) (*Arrow, error) {
+	arr := NewArrow(fa.SrcPort, fa.DstPort)
+	for _, fdt := range fa.DataTypes {
+		arr.AddDataType(fdt.Name, fdt.Type, fdt.Link)
+	}
+	for _, note := range fa.Notes {
+		arr.AddNote(note)
+	}
+	if fa.Error {
+		arr.MarkError()
+	}
+	change, err := ParseChange(fa.Change)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	arr.MarkChange(change)
+	switch {
+	case fa.To != nil && fa.LinkComp != "":
+		return nil, fmt.Errorf("%s: an arrow can't have a destination and link to a component", path)
+	case fa.To != nil:
+		dst, err := flow.endFromFile(fa.To, path+".to", links)
+		if err != nil {
+			return nil, err
+		}
+		arr.AddDestination(dst)
+	case fa.LinkComp != "":
+		*links = append(*links, pendingLink{arr: arr, id: fa.LinkComp, path: path})
+	default:
+		return nil, fmt.Errorf("%s: an arrow needs a destination or a component to link to", path)
+	}
+	return arr, nil
+}
@@ -0,0 +1,166 @@
+package draw_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+const smallTestFlowJSON = `{
+  "name": "smallTestFlow", "width": 1500,
+  "starts": [
+    {"startPort": "in", "output": {
+      "dataTypes": [{"name": "data", "type": "Data", "link": "https://google.com?q=Data"}],
+      "to": {"comp": {"name": "parse", "type": "Parser", "link": "https://google.com?q=Parser",
+        "plugins": [{"title": "semantics", "plugins": [
+          {"type": "TextSemantics", "link": "https://google.com?q=TextSemantics", "goLink": true}
+        ]}],
+        "outputs": [
+          {"srcPort": "out", "dataTypes": [{"name": "ast", "type": "AST"}],
+           "to": {"comp": {"type": "merge", "outputs": [
+             {"to": {"endPort": "out"}}
+           ]}}},
+          {"srcPort": "error", "dstPort": "in2", "linkComp": "merge"},
+          {"srcPort": "again", "to": {"loop": {"name": "parse", "port": "in", "link": "https://google.com?q=Parser"}}}
+        ]
+      }}
+    }}
+  ]
+}`
+
+func buildSmallTestFlow() *draw.Flow {
+	flow := draw.NewFlow("smallTestFlow", draw.FlowModeNoLinks, 1500, nil)
+	flow.AddStart(
+		draw.NewStartPort("in").AddOutput(
+			draw.NewArrow("", "").AddDataType(
+				"data", "Data", "https://google.com?q=Data").AddDestination(
+				draw.NewComp("parse", "Parser", "https://google.com?q=Parser", flow).AddPluginGroup(
+					draw.NewPluginGroup("semantics").AddPlugin(
+						draw.NewPlugin("TextSemantics", "https://google.com?q=TextSemantics").GoLink(),
+					),
+				).AddOutput(
+					draw.NewArrow("out", "").AddDataType("ast", "AST", "").AddDestination(
+						draw.NewComp("", "merge", "", flow).AddOutput(
+							draw.NewArrow("", "").AddDestination(draw.NewEndPort("out")),
+						),
+					),
+				).AddOutput(
+					draw.NewArrow("error", "in2").MustLinkComp("merge", flow),
+				).AddOutput(
+					draw.NewArrow("again", "").AddDestination(
+						draw.NewLoop("parse", "in", "https://google.com?q=Parser"),
+					),
+				),
+			),
+		),
+	)
+	return flow
+}
+
+func TestFlowFromJSON(t *testing.T) {
+	fileFlow, err := draw.FlowFromJSON([]byte(smallTestFlowJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedSVGs, expectedMD, err := buildSmallTestFlow().SetTextMetrics(draw.FontMetrics{}).Draw()
+	if err != nil {
+		t.Fatalf("unexpected error drawing the expected flow: %v", err)
+	}
+	actualSVGs, actualMD, err := fileFlow.Draw()
+	if err != nil {
+		t.Fatalf("unexpected error drawing the flow from JSON: %v", err)
+	}
+
+	if !bytes.Equal(actualMD, expectedMD) {
+		t.Errorf("expected MarkDown:\n%s\ngot:\n%s", expectedMD, actualMD)
+	}
+	if len(actualSVGs) != len(expectedSVGs) {
+		t.Fatalf("expected %d SVG files, got: %d", len(expectedSVGs), len(actualSVGs))
+	}
+	for name, expected := range expectedSVGs {
+		if actual := actualSVGs[name]; !bytes.Equal(actual, expected) {
+			t.Errorf("expected SVG %q:\n%s\ngot:\n%s", name, expected, actual)
+		}
+	}
+}
+
+func TestFlowFromJSONErrors(t *testing.T) {
+	specs := []struct {
+		name          string
+		givenJSON     string
+		expectedError string
+	}{
+		{
+			name:          "no-name",
+			givenJSON:     `{"starts": []}`,
+			expectedError: "missing name",
+		}, {
+			name:          "path-in-name",
+			givenJSON:     `{"name": "../../x", "starts": []}`,
+			expectedError: "can't contain path separators",
+		}, {
+			name:          "unknown-mode",
+			givenJSON:     `{"name": "f", "mode": "bla"}`,
+			expectedError: "unknown flow mode",
+		}, {
+			name:          "unknown-metrics",
+			givenJSON:     `{"name": "f", "metrics": "bla"}`,
+			expectedError: "unknown text metrics",
+		}, {
+			name:          "unknown-direction",
+			givenJSON:     `{"name": "f", "direction": "bla"}`,
+			expectedError: "unknown layout direction",
+		}, {
+			name:          "unknown-field",
+			givenJSON:     `{"name": "f", "bla": 1}`,
+			expectedError: "unknown field",
+		}, {
+			name:          "two-kinds",
+			givenJSON:     `{"name": "f", "starts": [{"startPort": "in", "endPort": "out"}]}`,
+			expectedError: "starts[0]: a node must be exactly one of",
+		}, {
+			name: "no-destination",
+			givenJSON: `{"name": "f", "starts": [{"startPort": "in", "output": {
+				"to": {"comp": {"type": "A", "outputs": [{"srcPort": "out"}]}}}}]}`,
+			expectedError: "starts[0].output.to.comp.outputs[0]: an arrow needs a destination",
+		}, {
+			name: "unknown-link",
+			givenJSON: `{"name": "f", "starts": [{"startPort": "in", "output": {
+				"linkComp": "bla"}}]}`,
+			expectedError: `starts[0].output: unable to link to component with ID: "bla"`,
+		}, {
+			name: "duplicate-comp",
+			givenJSON: `{"name": "f", "starts": [{"comp": {"type": "A", "outputs": [
+				{"to": {"comp": {"type": "A"}}}]}}]}`,
+			expectedError: `duplicate component with ID: "A"`,
+		}, {
+			name:          "unknown-kind",
+			givenJSON:     `{"name": "f", "starts": [{"comp": {"name": "x", "kind": "cloud"}}]}`,
+			expectedError: `starts[0].comp: unknown component kind "cloud"`,
+		}, {
+			name: "unknown-group-comp",
+			givenJSON: `{"name": "f", "starts": [{"comp": {"name": "a", "outputs": [{"to": {"endPort": "out"}}]}}],
+				"groups": [{"label": "none", "comps": ["x"]}]}`,
+			expectedError: `groups[0].comps[0]: unknown component with ID: "x"`,
+		}, {
+			name: "unknown-change",
+			givenJSON: `{"name": "f", "starts": [
+				{"comp": {"name": "a", "change": "moved", "outputs": [{"to": {"endPort": "out"}}]}}]}`,
+			expectedError: `unknown change "moved"`,
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			_, err := draw.FlowFromJSON([]byte(spec.givenJSON))
+			if err == nil {
+				t.Fatalf("expected error containing %q, got none", spec.expectedError)
+			}
+			if !strings.Contains(err.Error(), spec.expectedError) {
+				t.Errorf("expected error containing %q, got: %v", spec.expectedError, err)
+			}
+		})
+	}
+}
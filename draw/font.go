@@ -0,0 +1,157 @@
+package draw
+
+import (
+	"strings"
+)
+
+// The bitmap font used for PNG files has glyphs that are 5 pixels wide.
+// Rows 0 to 6 are above the base line and rows 7 and 8 are for descenders.
+const (
+	glyphCols      = 5
+	glyphRows      = 9
+	glyphCapRows   = 7
+	glyphCellWidth = glyphCols + 1 // including the gap to the next glyph
+)
+
+// glyph contains a bit mask for every row.
+// The most significant of the 5 bits is the leftmost pixel.
+type glyph [glyphRows]uint8
+
+// glyphPatterns describes the glyphs row by row from top to bottom.
+// Missing rows at the end are empty.
+var glyphPatterns = map[rune]string{
+	' ':  "",
+	'!':  "..#.. ..#.. ..#.. ..#.. ..#.. ..... ..#..",
+	'"':  ".#.#. .#.#. .#.#.",
+	'#':  ".#.#. .#.#. ##### .#.#. ##### .#.#. .#.#.",
+	'$':  "..#.. .#### #.#.. .###. ..#.# ####. ..#..",
+	'%':  "##... ##..# ...#. ..#.. .#... #..## ...##",
+	'&':  ".##.. #..#. #.#.. .#... #.#.# #..#. .##.#",
+	'\'': "..#.. ..#.. ..#..",
+	'(':  "...#. ..#.. .#... .#... .#... ..#.. ...#.",
+	')':  ".#... ..#.. ...#. ...#. ...#. ..#.. .#...",
+	'*':  "..... ..#.. #.#.# .###. #.#.# ..#.. .....",
+	'+':  "..... ..#.. ..#.. ##### ..#.. ..#.. .....",
+	',':  "..... ..... ..... ..... ..... .##.. .##.. ..#.. .#...",
+	'-':  "..... ..... ..... ##### .....",
+	'.':  "..... ..... ..... ..... ..... .##.. .##..",
+	'/':  "....# ...#. ...#. ..#.. .#... .#... #....",
+	'0':  ".###. #...# #..## #.#.# ##..# #...# .###.",
+	'1':  "..#.. .##.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'2':  ".###. #...# ....# ...#. ..#.. .#... #####",
+	'3':  "##### ...#. ..#.. ...#. ....# #...# .###.",
+	'4':  "...#. ..##. .#.#. #..#. ##### ...#. ...#.",
+	'5':  "##### #.... ####. ....# ....# #...# .###.",
+	'6':  "..##. .#... #.... ####. #...# #...# .###.",
+	'7':  "##### ....# ...#. ..#.. .#... .#... .#...",
+	'8':  ".###. #...# #...# .###. #...# #...# .###.",
+	'9':  ".###. #...# #...# .#### ....# ...#. .##..",
+	':':  "..... .##.. .##.. ..... .##.. .##.. .....",
+	';':  "..... .##.. .##.. ..... .##.. .##.. ..#.. .#...",
+	'<':  "...#. ..#.. .#... #.... .#... ..#.. ...#.",
+	'=':  "..... ..... ##### ..... ##### ..... .....",
+	'>':  ".#... ..#.. ...#. ....# ...#. ..#.. .#...",
+	'?':  ".###. #...# ....# ...#. ..#.. ..... ..#..",
+	'@':  ".###. #...# ....# .##.# #.#.# #.#.# .###.",
+	'A':  ".###. #...# #...# ##### #...# #...# #...#",
+	'B':  "####. #...# #...# ####. #...# #...# ####.",
+	'C':  ".###. #...# #.... #.... #.... #...# .###.",
+	'D':  "###.. #..#. #...# #...# #...# #..#. ###..",
+	'E':  "##### #.... #.... ####. #.... #.... #####",
+	'F':  "##### #.... #.... ####. #.... #.... #....",
+	'G':  ".###. #...# #.... #.### #...# #...# .####",
+	'H':  "#...# #...# #...# ##### #...# #...# #...#",
+	'I':  ".###. ..#.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'J':  "..### ...#. ...#. ...#. ...#. #..#. .##..",
+	'K':  "#...# #..#. #.#.. ##... #.#.. #..#. #...#",
+	'L':  "#.... #.... #.... #.... #.... #.... #####",
+	'M':  "#...# ##.## #.#.# #.#.# #...# #...# #...#",
+	'N':  "#...# #...# ##..# #.#.# #..## #...# #...#",
+	'O':  ".###. #...# #...# #...# #...# #...# .###.",
+	'P':  "####. #...# #...# ####. #.... #.... #....",
+	'Q':  ".###. #...# #...# #...# #.#.# #..#. .##.#",
+	'R':  "####. #...# #...# ####. #.#.. #..#. #...#",
+	'S':  ".#### #.... #.... .###. ....# ....# ####.",
+	'T':  "##### ..#.. ..#.. ..#.. ..#.. ..#.. ..#..",
+	'U':  "#...# #...# #...# #...# #...# #...# .###.",
+	'V':  "#...# #...# #...# #...# #...# .#.#. ..#..",
+	'W':  "#...# #...# #...# #.#.# #.#.# #.#.# .#.#.",
+	'X':  "#...# #...# .#.#. ..#.. .#.#. #...# #...#",
+	'Y':  "#...# #...# .#.#. ..#.. ..#.. ..#.. ..#..",
+	'Z':  "##### ....# ...#. ..#.. .#... #.... #####",
+	'[':  ".###. .#... .#... .#... .#... .#... .###.",
+	'\\': "#.... .#... .#... ..#.. ...#. ...#. ....#",
+	']':  ".###. ...#. ...#. ...#. ...#. ...#. .###.",
+	'^':  "..#.. .#.#. #...#",
+	'_':  "..... ..... ..... ..... ..... ..... #####",
+	'`':  ".#... ..#.. ...#.",
+	'a':  "..... ..... .###. ....# .#### #...# .####",
+	'b':  "#.... #.... #.##. ##..# #...# #...# ####.",
+	'c':  "..... ..... .###. #.... #.... #...# .###.",
+	'd':  "....# ....# .##.# #..## #...# #...# .####",
+	'e':  "..... ..... .###. #...# ##### #.... .###.",
+	'f':  "..##. .#..# .#... ###.. .#... .#... .#...",
+	'g':  "..... ..... .#### #...# #...# #...# .#### ....# .###.",
+	'h':  "#.... #.... #.##. ##..# #...# #...# #...#",
+	'i':  "..#.. ..... .##.. ..#.. ..#.. ..#.. .###.",
+	'j':  "...#. ..... ..##. ...#. ...#. ...#. ...#. #..#. .##..",
+	'k':  "#.... #.... #..#. #.#.. ##... #.#.. #..#.",
+	'l':  ".##.. ..#.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'm':  "..... ..... ##.#. #.#.# #.#.# #.#.# #.#.#",
+	'n':  "..... ..... #.##. ##..# #...# #...# #...#",
+	'o':  "..... ..... .###. #...# #...# #...# .###.",
+	'p':  "..... ..... ####. #...# #...# #...# ####. #.... #....",
+	'q':  "..... ..... .#### #...# #...# #...# .#### ....# ....#",
+	'r':  "..... ..... #.##. ##..# #.... #.... #....",
+	's':  "..... ..... .###. #.... .###. ....# ####.",
+	't':  ".#... .#... ###.. .#... .#... .#..# ..##.",
+	'u':  "..... ..... #...# #...# #...# #..## .##.#",
+	'v':  "..... ..... #...# #...# #...# .#.#. ..#..",
+	'w':  "..... ..... #...# #...# #.#.# #.#.# .#.#.",
+	'x':  "..... ..... #...# .#.#. ..#.. .#.#. #...#",
+	'y':  "..... ..... #...# #...# #...# #...# .#### ....# .###.",
+	'z':  "..... ..... ##### ...#. ..#.. .#... #####",
+	'{':  "...#. ..#.. ..#.. .#... ..#.. ..#.. ...#.",
+	'|':  "..#.. ..#.. ..#.. ..#.. ..#.. ..#.. ..#..",
+	'}':  ".#... ..#.. ..#.. ...#. ..#.. ..#.. .#...",
+	'~':  "..... ..... .#... #.#.# ...#. ..... .....",
+	'…':  "..... ..... ..... ..... ..... ..... #.#.#",
+}
+
+// missingGlyph is used for all runes without a glyph.
+var missingGlyph = parseGlyph("##### #...# #...# #...# #...# #...# #####")
+
+var glyphs = parseGlyphs()
+
+func parseGlyphs() map[rune]glyph {
+	gs := make(map[rune]glyph, len(glyphPatterns))
+	for r, pattern := range glyphPatterns {
+		gs[r] = parseGlyph(pattern)
+	}
+	return gs
+}
+
+func parseGlyph(pattern string) glyph {
+	var g glyph
+	for i, row := range strings.Fields(pattern) {
+		for _, c := range row {
+			g[i] <<= 1
+			if c == '#' {
+				g[i] |= 1
+			}
+		}
+	}
+	return g
+}
+
+// glyphFor returns the glyph of the rune and false if the rune doesn't take
+// any space at all.
+func glyphFor(r rune) (glyph, bool) {
+	if runeUnits(r, 1, 1) == 0 {
+		return glyph{}, false
+	}
+	if g, ok := glyphs[r]; ok {
+		return g, true
+	}
+	return missingGlyph, true
+}
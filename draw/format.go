@@ -0,0 +1,84 @@
+package draw
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the file format of the diagrams of a flow.
+type Format int
+
+const (
+	// FormatSVG creates SVG files. It is the default.
+	FormatSVG Format = iota
+	// FormatPNG creates PNG files with a built-in bitmap font.
+	FormatPNG
+	// FormatPDF creates single page PDF files using the Helvetica font of
+	// the PDF viewer.
+	FormatPDF
+)
+
+// ParseFormat returns the format with the given name ("svg", "png" or
+// "pdf").
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "svg":
+		return FormatSVG, nil
+	case "png":
+		return FormatPNG, nil
+	case "pdf":
+		return FormatPDF, nil
+	default:
+		return FormatSVG, fmt.Errorf("unknown format %q (expected 'svg', 'png' or 'pdf')", name)
+	}
+}
+
+// ext returns the file extension of the format including the dot.
+func (f Format) ext() string {
+	switch f {
+	case FormatPNG:
+		return ".png"
+	case FormatPDF:
+		return ".pdf"
+	default:
+		return ".svg"
+	}
+}
+
+// SetFormat sets the file format of the diagrams.
+// PNG and PDF files can't switch colors, so for an AutoTheme only the light
+// colors are used unless UsePictures is set.
+func (flow *Flow) SetFormat(format Format) *Flow {
+	flow.format = format
+	return flow
+}
+
+// renameDiagrams changes the file extension of all diagrams and their links
+// in the MarkDown from ".svg" to the one of the format.
+func renameDiagrams(smf *svgMDFlow, format Format) {
+	if format == FormatSVG {
+		return
+	}
+	smf.rename(func(name string) string {
+		return strings.TrimSuffix(name, ".svg") + format.ext()
+	})
+}
+
+// svgFlowToFormat converts a single SVG flow to the bytes of the format.
+func svgFlowToFormat(sf *svgFlow, theme *Theme, format Format) ([]byte, error) {
+	switch format {
+	case FormatPNG:
+		return svgFlowToPNG(sf, theme.lightOnly())
+	case FormatPDF:
+		return svgFlowToPDF(sf, theme.lightOnly())
+	default:
+		return svgFlowToBytes(sf, theme)
+	}
+}
+
+// darkName returns the name of the dark variant of a diagram file.
+func darkName(name string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-dark" + ext
+}
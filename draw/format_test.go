@@ -0,0 +1,191 @@
+package draw_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+	"github.com/rogpeppe/go-internal/txtar"
+)
+
+func TestDrawPNG(t *testing.T) {
+	svgs, _, err := buildSmallTestFlow().Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := regexp.MustCompile(`width="(\d+)px" height="(\d+)px"`).FindSubmatch(svgs["flowdev/flow-smallTestFlow.svg"])
+	expectedWidth, _ := strconv.Atoi(string(m[1]))
+	expectedHeight, _ := strconv.Atoi(string(m[2]))
+
+	specs := []struct {
+		name               string
+		givenTheme         *draw.Theme
+		givenPictures      bool
+		expectedFiles      []string
+		expectedBackground color.RGBA
+	}{
+		{
+			name:               "light",
+			givenTheme:         draw.LightTheme(),
+			expectedFiles:      []string{"flowdev/flow-smallTestFlow.png"},
+			expectedBackground: color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		}, {
+			name:               "auto",
+			givenTheme:         draw.AutoTheme(draw.LightTheme(), draw.DarkTheme()),
+			expectedFiles:      []string{"flowdev/flow-smallTestFlow.png"},
+			expectedBackground: color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		}, {
+			name:          "pictures",
+			givenTheme:    draw.AutoTheme(draw.LightTheme(), draw.DarkTheme()),
+			givenPictures: true,
+			expectedFiles: []string{
+				"flowdev/flow-smallTestFlow.png",
+				"flowdev/flow-smallTestFlow-dark.png",
+			},
+			expectedBackground: color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			flow := buildSmallTestFlow().SetTheme(spec.givenTheme).SetFormat(draw.FormatPNG)
+			if spec.givenPictures {
+				flow.UsePictures()
+			}
+			files, md, err := flow.Draw()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(files) != len(spec.expectedFiles) {
+				t.Errorf("expected %d files, got: %d", len(spec.expectedFiles), len(files))
+			}
+			for _, name := range spec.expectedFiles {
+				if !strings.Contains(string(md), name) {
+					t.Errorf("expected MarkDown to link %q, got:\n%s", name, md)
+				}
+			}
+
+			img, err := png.Decode(bytes.NewReader(files[spec.expectedFiles[0]]))
+			if err != nil {
+				t.Fatalf("unable to decode PNG: %v", err)
+			}
+			bounds := img.Bounds()
+			if bounds.Dx() != 2*expectedWidth || bounds.Dy() != 2*expectedHeight {
+				t.Errorf("expected PNG size %dx%d, got: %dx%d",
+					2*expectedWidth, 2*expectedHeight, bounds.Dx(), bounds.Dy())
+			}
+			if actual := color.RGBAModel.Convert(img.At(1, bounds.Dy()-2)); actual != spec.expectedBackground {
+				t.Errorf("expected background %v, got: %v", spec.expectedBackground, actual)
+			}
+		})
+	}
+}
+
+func TestDrawPDF(t *testing.T) {
+	files, md, err := buildSmallTestFlow().SetFormat(draw.FormatPDF).Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(md), "flowdev/flow-smallTestFlow.pdf") {
+		t.Errorf("expected MarkDown to link the PDF file, got:\n%s", md)
+	}
+	pdf := files["flowdev/flow-smallTestFlow.pdf"]
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("expected a PDF document, got:\n%s", pdf)
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatalf("expected startxref in PDF, got:\n%s", pdf)
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Errorf("expected cross reference table at offset %d, got: %q", xref, pdf[xref:min(xref+20, len(pdf))])
+	}
+	for _, expected := range []string{"/BaseFont /Helvetica", "(parse) Tj", "(TextSemantics) Tj"} {
+		if !bytes.Contains(pdf, []byte(expected)) {
+			t.Errorf("expected PDF to contain %q, got:\n%s", expected, pdf)
+		}
+	}
+}
+
+func TestDrawFormatBadColor(t *testing.T) {
+	theme := draw.LightTheme()
+	theme.Comp = "lightblue"
+	_, _, err := buildSmallTestFlow().SetTheme(theme).SetFormat(draw.FormatPNG).Draw()
+	if err == nil || !strings.Contains(err.Error(), `unsupported color "lightblue"`) {
+		t.Errorf("expected unsupported color error, got: %v", err)
+	}
+}
+
+// archiveFlow reads a flow file from a testscript archive in the testdata
+// directory, so the PNG and PDF tests draw the same flows as the golden SVGs.
+func archiveFlow(t *testing.T, archive, name string) *draw.Flow {
+	t.Helper()
+	ar, err := txtar.ParseFile(filepath.Join("testdata", archive))
+	if err != nil {
+		t.Fatalf("unable to read archive %q: %v", archive, err)
+	}
+	for _, f := range ar.Files {
+		if f.Name == name {
+			flow, err := draw.FlowFromJSON(f.Data)
+			if err != nil {
+				t.Fatalf("unable to read flow file %q: %v", name, err)
+			}
+			return flow
+		}
+	}
+	t.Fatalf("flow file %q not found in archive %q", name, archive)
+	return nil
+}
+
+// drawFile draws the flow in the format and returns the only file.
+func drawFile(t *testing.T, flow *draw.Flow, format draw.Format) []byte {
+	t.Helper()
+	files, _, err := flow.SetFormat(format).Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got: %d", len(files))
+	}
+	for _, content := range files {
+		return content
+	}
+	return nil
+}
+
+// pngImage is a decoded PNG diagram with the origin of its SVG viewBox.
+type pngImage struct {
+	img    image.Image
+	x0, y0 int
+}
+
+// drawPNGImage draws the flow as PNG, decodes it and checks that it has got
+// twice the size of the SVG with the given viewBox.
+func drawPNGImage(t *testing.T, flow *draw.Flow, x0, y0, width, height int) *pngImage {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(drawFile(t, flow, draw.FormatPNG)))
+	if err != nil {
+		t.Fatalf("unable to decode PNG: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 2*width || bounds.Dy() != 2*height {
+		t.Errorf("expected PNG size %dx%d, got: %dx%d", 2*width, 2*height, bounds.Dx(), bounds.Dy())
+	}
+	return &pngImage{img: img, x0: x0, y0: y0}
+}
+
+// checkColor checks the color of the pixel at the SVG coordinates.
+func (pi *pngImage) checkColor(t *testing.T, what string, x, y float64, expected color.RGBA) {
+	t.Helper()
+	px, py := int((x-float64(pi.x0))*2), int((y-float64(pi.y0))*2)
+	if actual := color.RGBAModel.Convert(pi.img.At(px, py)); actual != expected {
+		t.Errorf("expected %s at %v,%v to be %v, got: %v", what, x, y, expected, actual)
+	}
+}
@@ -0,0 +1,262 @@
+package draw
+
+import (
+	"sort"
+)
+
+// The layered layout is a simple Sugiyama style layout:
+// 1. Every shape is assigned to a layer (the length of the longest path to
+//    it from a start of its cluster). Arrows spanning multiple layers get
+//    virtual nodes in the layers in between.
+// 2. The order of the shapes in each layer is changed to reduce the number
+//    of crossing arrows (barycenter heuristic).
+//    The new order is applied to the outputs of components and the starts
+//    of a cluster.
+// 3. All shapes of a layer start at the same x position.
+
+const layeredSweeps = 8 // number of down and up sweeps for crossing reduction
+
+// UseLayeredLayout lets the flow be laid out in layers with as few crossing
+// arrows as possible.
+// This helps with complex flows containing many merges.
+func (flow *Flow) UseLayeredLayout() *Flow {
+	flow.layered = true
+	return flow
+}
+
+// layerNode is a shape or a virtual node for an arrow spanning multiple
+// layers.
+type layerNode struct {
+	comp  anyComp // nil for virtual nodes
+	pos   int
+	preds []*layerNode
+	succs []*layerNode
+}
+
+func (flow *Flow) reduceCrossings() {
+	for _, cl := range flow.clusters {
+		cl.reduceCrossings()
+	}
+}
+
+func (cl *Cluster) reduceCrossings() {
+	comps, ranks := cl.rankComps()
+	nodes := make(map[anyComp]*layerNode, len(comps))
+	layers := make([][]*layerNode, 0, 16)
+	addNode := func(n *layerNode, rank int) {
+		for len(layers) <= rank {
+			layers = append(layers, make([]*layerNode, 0, 16))
+		}
+		n.pos = len(layers[rank])
+		layers[rank] = append(layers[rank], n)
+	}
+	for _, comp := range comps {
+		n := &layerNode{comp: comp}
+		nodes[comp] = n
+		addNode(n, ranks[comp])
+	}
+
+	next := make(map[*Arrow]*layerNode, len(comps)) // first node after the arrow
+	for _, comp := range comps {
+		src := nodes[comp]
+		for _, out := range outputsOf(comp) {
+			dst := nodes[out.dstComp]
+			prev := src
+			for r := ranks[comp] + 1; r < ranks[out.dstComp]; r++ {
+				virt := &layerNode{}
+				addNode(virt, r)
+				linkLayerNodes(prev, virt)
+				if prev == src {
+					next[out] = virt
+				}
+				prev = virt
+			}
+			linkLayerNodes(prev, dst)
+			if prev == src {
+				next[out] = dst
+			}
+		}
+	}
+
+	orderLayers(layers)
+
+	for _, comp := range comps {
+		if c, ok := comp.(*Comp); ok {
+			sort.SliceStable(c.outputs, func(i, j int) bool {
+				return next[c.outputs[i]].pos < next[c.outputs[j]].pos
+			})
+		}
+	}
+	sort.SliceStable(cl.starts, func(i, j int) bool {
+		return nodes[cl.starts[i]].pos < nodes[cl.starts[j]].pos
+	})
+
+	cl.layers = make([][]anyComp, len(layers))
+	for i, layer := range layers {
+		for _, n := range layer {
+			if n.comp != nil {
+				cl.layers[i] = append(cl.layers[i], n.comp)
+			}
+		}
+	}
+}
+
+func linkLayerNodes(src, dst *layerNode) {
+	src.succs = append(src.succs, dst)
+	dst.preds = append(dst.preds, src)
+}
+
+// rankComps returns all shapes of the cluster in depth first order and
+// their layers.
+func (cl *Cluster) rankComps() ([]anyComp, map[anyComp]int) {
+	comps := make([]anyComp, 0, 64)
+	inputs := make(map[anyComp]int, 64)
+	seen := make(map[anyComp]bool, 64)
+	var visit func(comp anyComp)
+	visit = func(comp anyComp) {
+		if seen[comp] {
+			return
+		}
+		seen[comp] = true
+		comps = append(comps, comp)
+		for _, out := range outputsOf(comp) {
+			inputs[out.dstComp]++
+			visit(out.dstComp)
+		}
+	}
+	for _, start := range cl.starts {
+		visit(start)
+	}
+
+	// longest path from the starts (topological order):
+	ranks := make(map[anyComp]int, len(comps))
+	queue := make([]anyComp, 0, len(comps))
+	for _, comp := range comps {
+		if inputs[comp] == 0 {
+			queue = append(queue, comp)
+		}
+	}
+	for len(queue) > 0 {
+		comp := queue[0]
+		queue = queue[1:]
+		for _, out := range outputsOf(comp) {
+			dst := out.dstComp
+			ranks[dst] = max(ranks[dst], ranks[comp]+1)
+			inputs[dst]--
+			if inputs[dst] == 0 {
+				queue = append(queue, dst)
+			}
+		}
+	}
+	return comps, ranks
+}
+
+func outputsOf(comp anyComp) []*Arrow {
+	switch c := comp.(type) {
+	case *Comp:
+		return c.outputs
+	case *StartPort:
+		return []*Arrow{c.output}
+	case *BreakEnd:
+		return []*Arrow{c.output}
+	default:
+		return nil
+	}
+}
+
+// orderLayers reduces the crossings between the layers by sorting the
+// nodes of each layer by the average position of their neighbors.
+// The best order found is kept.
+func orderLayers(layers [][]*layerNode) {
+	best := savePositions(layers)
+	bestCrossings := countCrossings(layers)
+	for i := 0; i < layeredSweeps && bestCrossings > 0; i++ {
+		if i%2 == 0 {
+			for r := 1; r < len(layers); r++ {
+				sortByBarycenter(layers[r], func(n *layerNode) []*layerNode { return n.preds })
+			}
+		} else {
+			for r := len(layers) - 2; r >= 0; r-- {
+				sortByBarycenter(layers[r], func(n *layerNode) []*layerNode { return n.succs })
+			}
+		}
+		if crossings := countCrossings(layers); crossings < bestCrossings {
+			best = savePositions(layers)
+			bestCrossings = crossings
+		}
+	}
+	for r, layer := range layers {
+		copy(layer, best[r])
+		for i, n := range layer {
+			n.pos = i
+		}
+	}
+}
+
+func sortByBarycenter(layer []*layerNode, neighbors func(*layerNode) []*layerNode) {
+	centers := make(map[*layerNode]float64, len(layer))
+	for _, n := range layer {
+		ns := neighbors(n)
+		if len(ns) == 0 {
+			centers[n] = float64(n.pos)
+			continue
+		}
+		sum := 0
+		for _, nn := range ns {
+			sum += nn.pos
+		}
+		centers[n] = float64(sum) / float64(len(ns))
+	}
+	sort.SliceStable(layer, func(i, j int) bool {
+		return centers[layer[i]] < centers[layer[j]]
+	})
+	for i, n := range layer {
+		n.pos = i
+	}
+}
+
+func savePositions(layers [][]*layerNode) [][]*layerNode {
+	saved := make([][]*layerNode, len(layers))
+	for r, layer := range layers {
+		saved[r] = append([]*layerNode(nil), layer...)
+	}
+	return saved
+}
+
+func countCrossings(layers [][]*layerNode) int {
+	crossings := 0
+	for _, layer := range layers {
+		type edge struct{ src, dst int }
+		edges := make([]edge, 0, len(layer))
+		for _, n := range layer {
+			for _, s := range n.succs {
+				edges = append(edges, edge{src: n.pos, dst: s.pos})
+			}
+		}
+		for i, e1 := range edges {
+			for _, e2 := range edges[i+1:] {
+				if (e1.src < e2.src && e1.dst > e2.dst) || (e1.src > e2.src && e1.dst < e2.dst) {
+					crossings++
+				}
+			}
+		}
+	}
+	return crossings
+}
+
+// alignLayers moves all shapes of a layer to the same x position.
+func (flow *Flow) alignLayers() {
+	for _, cl := range flow.clusters {
+		for _, layer := range cl.layers {
+			x0 := 0
+			for _, comp := range layer {
+				x0 = max(x0, comp.getDrawData().x0)
+			}
+			for _, comp := range layer {
+				if comp.getDrawData().x0 < x0 {
+					comp.calcHorizontalValues(x0)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package draw_test
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+// buildCrossingTestFlow builds a flow whose arrows "toM" and "fromB" cross
+// if the outputs of comp A are drawn in the order they have been added.
+func buildCrossingTestFlow() *draw.Flow {
+	flow := draw.NewFlow("crossingTestFlow", draw.FlowModeNoLinks, 1500, nil)
+	flow.AddStart(
+		draw.NewStartPort("in").AddOutput(
+			draw.NewArrow("", "").AddDestination(
+				draw.NewComp("", "S", "", flow).AddOutput(
+					draw.NewArrow("", "").AddDestination(
+						draw.NewComp("", "A", "", flow).AddOutput(
+							draw.NewArrow("toM", "in1").AddDestination(
+								draw.NewComp("", "M", "", flow).AddOutput(
+									draw.NewArrow("", "").AddDestination(draw.NewEndPort("out1")),
+								),
+							),
+						).AddOutput(
+							draw.NewArrow("toP", "").AddDestination(
+								draw.NewComp("", "P", "", flow).AddOutput(
+									draw.NewArrow("", "").AddDestination(draw.NewEndPort("out2")),
+								),
+							),
+						),
+					),
+				).AddOutput(
+					draw.NewArrow("", "").AddDestination(
+						draw.NewComp("", "B", "", flow).AddOutput(
+							draw.NewArrow("fromB", "in2").MustLinkComp("M", flow),
+						),
+					),
+				),
+			),
+		),
+	)
+	return flow
+}
+
+func TestLayeredLayout(t *testing.T) {
+	specs := []struct {
+		name            string
+		givenLayered    bool
+		expectedPFirst  bool
+		expectedAligned bool
+	}{
+		{
+			name:            "default",
+			givenLayered:    false,
+			expectedPFirst:  false,
+			expectedAligned: false,
+		}, {
+			name:            "layered",
+			givenLayered:    true,
+			expectedPFirst:  true,
+			expectedAligned: true,
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			flow := buildCrossingTestFlow()
+			if spec.givenLayered {
+				flow.UseLayeredLayout()
+			}
+			svgs, _, err := flow.Draw()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			svg := string(svgs["flowdev/flow-crossingTestFlow.svg"])
+
+			_, toPY := textPosition(t, svg, "toP")
+			_, toMY := textPosition(t, svg, "toM")
+			if actualPFirst := toPY < toMY; actualPFirst != spec.expectedPFirst {
+				t.Errorf("expected arrow to P above arrow to M: %t, got: %t", spec.expectedPFirst, actualPFirst)
+			}
+
+			out1X, _ := textPosition(t, svg, "out1")
+			out2X, _ := textPosition(t, svg, "out2")
+			if actualAligned := out1X == out2X; actualAligned != spec.expectedAligned {
+				t.Errorf("expected aligned end ports: %t, got: %t (%d and %d)",
+					spec.expectedAligned, actualAligned, out1X, out2X)
+			}
+		})
+	}
+}
+
+func textPosition(t *testing.T, svg, text string) (x, y int) {
+	t.Helper()
+	re := regexp.MustCompile(`x="(\d+)" y="(\d+)"[^>]*>` + regexp.QuoteMeta(text) + `<`)
+	m := re.FindStringSubmatch(svg)
+	if m == nil {
+		t.Fatalf("text %q not found in SVG:\n%s", text, svg)
+	}
+	x, _ = strconv.Atoi(m[1])
+	y, _ = strconv.Atoi(m[2])
+	return x, y
+}
@@ -0,0 +1,345 @@
+package draw
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// LayoutOptions configure how a flow is laid out.
+type LayoutOptions struct {
+	Mode      FlowMode
+	Width     int
+	Direction LayoutDirection
+	Layered   bool
+	Routing   bool
+	HappyPath bool        // hide error paths
+	Metrics   TextMetrics // FixedMetrics if nil
+}
+
+// RenderOptions configure how a layout is turned into files.
+type RenderOptions struct {
+	Theme       *Theme // LightTheme if nil
+	Pictures    bool
+	Format      Format
+	Naming      Naming // DefaultNaming if nil
+	SharedTiles bool
+}
+
+// Layout is the computed layout of a flow.
+// It is never changed after it has been created. So it can be rendered
+// many times with different options, even concurrently.
+type Layout struct {
+	name             string
+	mode             FlowMode
+	direction        LayoutDirection
+	breaks           int
+	maxWidth         int
+	width, height    int
+	smf              *svgMDFlow
+	names            nameRecorder
+	description      string
+	portDescriptions []portDescription
+}
+
+// LayoutOptions returns the layout options configured for the flow.
+func (flow *Flow) LayoutOptions() LayoutOptions {
+	return LayoutOptions{
+		Mode:      flow.mode,
+		Width:     flow.width,
+		Direction: flow.direction,
+		Layered:   flow.layered,
+		Routing:   flow.routing,
+		HappyPath: flow.happyPath,
+		Metrics:   flow.textMetrics(),
+	}
+}
+
+// RenderOptions returns the render options configured for the flow.
+func (flow *Flow) RenderOptions() RenderOptions {
+	return RenderOptions{
+		Theme:       flow.theme,
+		Pictures:    flow.pictures,
+		Format:      flow.format,
+		Naming:      flow.naming,
+		SharedTiles: flow.sharedTiles,
+	}
+}
+
+// Layout lays out the flow with the given options.
+// The flow itself isn't changed, so many layouts can be computed from the
+// same flow, even concurrently.
+// If the flow data isn't valid, an error is returned.
+func (flow *Flow) Layout(opts LayoutOptions) (*Layout, error) {
+	if err := flow.Validate(); err != nil {
+		return nil, err
+	}
+	names := make(nameRecorder, 256)
+	work := &Flow{ // all intermediate layout data goes here
+		name:      flow.name,
+		mode:      opts.Mode,
+		width:     opts.Width,
+		theme:     flow.theme,
+		metrics:   opts.Metrics,
+		direction: opts.Direction,
+		layered:   opts.Layered,
+		routing:   opts.Routing,
+		naming:    names,
+		starts:    flow.starts,
+		groups:    flow.groups,
+		copies:    make(map[*Comp]*Comp, len(flow.compRegistry)),
+	}
+	if opts.HappyPath {
+		work.happyComps = happyComps(flow.starts)
+	}
+
+	work.copyAllClusters()
+	if work.layered {
+		work.reduceCrossings()
+	}
+	var smf *svgMDFlow
+	width, height := 0, 0
+	if work.direction == LayoutTopDown {
+		smf = work.topDownToSVGs()
+		width, height = smf.svgs[""].TotalWidth, smf.svgs[""].TotalHeight
+	} else {
+		work.calcHorizontalValues()
+		if work.layered {
+			work.alignLayers()
+		}
+		work.extendArrows()
+		work.respectMaxWidth()
+		work.calcVerticalValues()
+		smf = flowToSVGs(work)
+		if work.routing && work.mode != FlowModeMDLinks {
+			work.routeEdges(smf.svgs[""])
+		}
+		width, height = work.drawData.width, work.drawData.height
+	}
+	if work.mode != FlowModeMDLinks || work.direction == LayoutTopDown {
+		work.groupsToSVG(smf.svgs[""])
+		svgName := names.Diagram(work.name)
+		smf.svgs[svgName] = smf.svgs[""]
+		delete(smf.svgs, "")
+		smf.md.FlowLines = append(smf.md.FlowLines, make([]*svgLink, 1))
+		smf.md.FlowLines[0][0] = &svgLink{
+			Name: work.name,
+			SVG:  svgName,
+		}
+	}
+	if measured(opts.Metrics) {
+		for _, sf := range smf.svgs {
+			sf.Measured = true
+		}
+	}
+
+	return &Layout{
+		name:             flow.name,
+		mode:             opts.Mode,
+		direction:        opts.Direction,
+		breaks:           work.countBreaks(),
+		maxWidth:         opts.Width,
+		width:            width,
+		height:           height,
+		smf:              smf,
+		names:            names,
+		description:      flow.description,
+		portDescriptions: append([]portDescription(nil), flow.portDescriptions...),
+	}, nil
+}
+
+// Breaks returns the number of rows that had to be broken to respect the
+// maximum width.
+func (l *Layout) Breaks() int {
+	return l.breaks
+}
+
+// MaxWidth returns the maximum width the flow has been laid out for.
+func (l *Layout) MaxWidth() int {
+	return l.maxWidth
+}
+
+// Width returns the width of the laid out flow.
+func (l *Layout) Width() int {
+	return l.width
+}
+
+// Height returns the height of the laid out flow.
+func (l *Layout) Height() int {
+	return l.height
+}
+
+// countBreaks returns the number of breaks in the laid out clusters.
+func (flow *Flow) countBreaks() int {
+	n := 0
+	for _, cl := range flow.clusters {
+		for _, start := range cl.starts {
+			if _, ok := start.(*BreakEnd); ok {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Render creates a set of diagrams and a MarkDown file from the layout.
+// If the diagrams or the MarkDown file can't be created, an error is
+// returned.
+func (l *Layout) Render(opts RenderOptions) (svgContents map[string][]byte, mdContent []byte, err error) {
+	svgContents, md, err := l.render(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	mdContent, err = mdFlowToBytes(md)
+	if err != nil {
+		return nil, nil,
+			fmt.Errorf("unable to create MarkDown content for %q flow: %w", l.name, err)
+	}
+	return svgContents, mdContent, nil
+}
+
+func (l *Layout) render(opts RenderOptions) (map[string][]byte, *mdFlow, error) {
+	smf := l.smf.clone()
+	naming := opts.Naming
+	if naming == nil {
+		naming = DefaultNaming
+	}
+	theme := themeOrDefault(opts.Theme)
+
+	smf.rename(func(key string) string {
+		return l.names[key](naming)
+	})
+	shared := opts.SharedTiles && l.mode == FlowModeMDLinks && l.direction != LayoutTopDown
+	if shared {
+		moveTilesToOrigin(smf.svgs)
+	}
+	renameDiagrams(smf, opts.Format)
+	var svgContents map[string][]byte
+	var err error
+	if opts.Pictures && theme.Dark != nil {
+		svgContents, err = picturesToBytes(smf, theme, opts.Format)
+	} else {
+		svgContents, err = svgFlowsToBytes(smf.svgs, theme, opts.Format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if shared {
+		svgContents = shareTiles(svgContents, smf.md, naming)
+	}
+	smf.md.Description = l.description
+	smf.md.Ports = l.portDescriptions
+	return svgContents, smf.md, nil
+}
+
+// nameRecorder is the naming used while laying out a flow.
+// It hands out unique keys and records how to name them with the naming
+// used for rendering.
+type nameRecorder map[string]func(Naming) string
+
+func (nr nameRecorder) add(name func(Naming) string) string {
+	key := "#" + strconv.Itoa(len(nr)) + ".svg"
+	nr[key] = name
+	return key
+}
+
+func (nr nameRecorder) Diagram(flow string) string {
+	return nr.add(func(n Naming) string { return n.Diagram(flow) })
+}
+
+func (nr nameRecorder) Tile(flow string, idx, line int, comp string) string {
+	return nr.add(func(n Naming) string { return n.Tile(flow, idx, line, comp) })
+}
+
+func (nr nameRecorder) Filler(flow string, width, height int) string {
+	return nr.add(func(n Naming) string { return n.Filler(flow, width, height) })
+}
+
+func (nr nameRecorder) SharedTile(hash string) string {
+	return nr.add(func(n Naming) string { return n.SharedTile(hash) })
+}
+
+// rename renames all diagrams and their links in the MarkDown.
+func (smf *svgMDFlow) rename(name func(string) string) {
+	svgs := make(map[string]*svgFlow, len(smf.svgs))
+	for key, sf := range smf.svgs {
+		svgs[name(key)] = sf
+	}
+	smf.svgs = svgs
+	if smf.md.Flow.SVG != "" {
+		smf.md.Flow.SVG = name(smf.md.Flow.SVG)
+	}
+	for _, flowLine := range smf.md.FlowLines {
+		for _, cell := range flowLine {
+			cell.SVG = name(cell.SVG)
+		}
+	}
+}
+
+// clone returns a deep copy of the diagrams and the MarkDown flow, so they
+// can be changed while rendering.
+func (smf *svgMDFlow) clone() *svgMDFlow {
+	dst := &svgMDFlow{
+		svgs:     make(map[string]*svgFlow, len(smf.svgs)),
+		md:       newMDFlow(),
+		naming:   smf.naming,
+		flowName: smf.flowName,
+	}
+	for key, sf := range smf.svgs {
+		dst.svgs[key] = sf.clone()
+	}
+	dst.md.Flow = smf.md.Flow
+	for _, flowLine := range smf.md.FlowLines {
+		line := make([]*svgLink, len(flowLine))
+		for i, cell := range flowLine {
+			c := *cell
+			line[i] = &c
+		}
+		dst.md.FlowLines = append(dst.md.FlowLines, line)
+	}
+	for _, links := range []struct{ src, dst map[string]string }{
+		{smf.md.DataTypes, dst.md.DataTypes},
+		{smf.md.Subflows, dst.md.Subflows},
+		{smf.md.GoFuncs, dst.md.GoFuncs},
+	} {
+		for name, link := range links.src {
+			links.dst[name] = link
+		}
+	}
+	return dst
+}
+
+func (sf *svgFlow) clone() *svgFlow {
+	dst := *sf
+	dst.Groups = make([]*svgGroup, len(sf.Groups))
+	for i, g := range sf.Groups {
+		c := *g
+		dst.Groups[i] = &c
+	}
+	dst.Arrows = make([]*svgArrow, len(sf.Arrows))
+	for i, a := range sf.Arrows {
+		c := *a
+		dst.Arrows[i] = &c
+	}
+	dst.Paths = make([]*svgPath, len(sf.Paths))
+	for i, p := range sf.Paths {
+		c := *p
+		c.Points = append([]svgPoint(nil), p.Points...)
+		dst.Paths[i] = &c
+	}
+	dst.Rects = make([]*svgRect, len(sf.Rects))
+	for i, r := range sf.Rects {
+		c := *r
+		dst.Rects[i] = &c
+	}
+	dst.Markers = make([]*svgMarker, len(sf.Markers))
+	for i, m := range sf.Markers {
+		c := *m
+		dst.Markers[i] = &c
+	}
+	dst.Texts = make([]*svgText, len(sf.Texts))
+	for i, t := range sf.Texts {
+		c := *t
+		dst.Texts[i] = &c
+	}
+	return &dst
+}
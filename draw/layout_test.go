@@ -0,0 +1,116 @@
+package draw_test
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestLayoutConcurrently(t *testing.T) {
+	type config struct {
+		mode  draw.FlowMode
+		width int
+		theme *draw.Theme
+	}
+	configs := []config{
+		{mode: draw.FlowModeNoLinks, width: 1500, theme: draw.LightTheme()},
+		{mode: draw.FlowModeNoLinks, width: 750, theme: draw.DarkTheme()},
+		{mode: draw.FlowModeMDLinks, width: 1500, theme: draw.LightTheme()},
+		{mode: draw.FlowModeMDLinks, width: 600, theme: draw.AutoTheme(nil, nil)},
+	}
+	drawWith := func(flow *draw.Flow, c config) (map[string][]byte, []byte, error) {
+		opts := flow.LayoutOptions()
+		opts.Mode, opts.Width = c.mode, c.width
+		layout, err := flow.Layout(opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return layout.Render(draw.RenderOptions{Theme: c.theme})
+	}
+
+	// expected results are drawn with a fresh flow for every config:
+	expectedSVGs := make([]map[string][]byte, len(configs))
+	expectedMDs := make([][]byte, len(configs))
+	for i, c := range configs {
+		flow := buildBigTestFlowData()
+		flow.ChangeConfig("bigTestFlow", c.mode, c.width, c.theme)
+		svgs, md, err := flow.Draw()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectedSVGs[i], expectedMDs[i] = svgs, md
+	}
+
+	flow := buildBigTestFlowData()
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*len(configs))
+	for n := 0; n < 4; n++ {
+		for i, c := range configs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				svgs, md, err := drawWith(flow, c)
+				if err != nil {
+					errs <- err
+					return
+				}
+				errs <- compareDrawing(expectedSVGs[i], expectedMDs[i], svgs, md)
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestLayoutRenderTwice(t *testing.T) {
+	flow := buildBigTestFlowData()
+	flow.ChangeConfig("bigTestFlow", draw.FlowModeMDLinks, 1500, nil)
+	layout, err := flow.Layout(flow.LayoutOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	shared := draw.RenderOptions{SharedTiles: true, Naming: draw.PrefixNaming{Dir: "tiles"}}
+	for _, opts := range []draw.RenderOptions{{}, shared, {Format: draw.FormatPNG}, {}, shared} {
+		svgs, md, err := layout.Render(opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectedFlow := buildBigTestFlowData()
+		expectedFlow.ChangeConfig("bigTestFlow", draw.FlowModeMDLinks, 1500, nil)
+		expectedFlow.SetFormat(opts.Format).SetNaming(opts.Naming)
+		if opts.SharedTiles {
+			expectedFlow.UseSharedTiles()
+		}
+		expectedSVGs, expectedMD, err := expectedFlow.Draw()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err = compareDrawing(expectedSVGs, expectedMD, svgs, md); err != nil {
+			t.Errorf("render options %+v: %v", opts, err)
+		}
+	}
+}
+
+func compareDrawing(expectedSVGs map[string][]byte, expectedMD []byte, svgs map[string][]byte, md []byte) error {
+	if !bytes.Equal(md, expectedMD) {
+		return fmt.Errorf("expected MarkDown:\n%s\ngot:\n%s", expectedMD, md)
+	}
+	if len(svgs) != len(expectedSVGs) {
+		return fmt.Errorf("expected %d diagrams, got: %d", len(expectedSVGs), len(svgs))
+	}
+	for name, expected := range expectedSVGs {
+		if !bytes.Equal(svgs[name], expected) {
+			return fmt.Errorf("diagram %q differs", name)
+		}
+	}
+	return nil
+}
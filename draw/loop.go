@@ -37,8 +37,7 @@ func (loop *Loop) minRestOfRowWidth(num int) int {
 }
 
 func (loop *Loop) calcHorizontalValues(x0 int) {
-	txt := loop.name + loop.port
-	width := BreakWidth + LoopWidth + len(txt)*CharWidth
+	width := BreakWidth + LoopWidth + loop.textWidth(loop.name, false) + loop.textWidth(loop.port, false)
 	if loop.port != "" {
 		width += CharWidth / 2
 	}
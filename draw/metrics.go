@@ -0,0 +1,223 @@
+package draw
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TextMetrics measures the width of texts in pixels.
+// Small texts are used for port names on arrows.
+// All other texts have the normal size.
+type TextMetrics interface {
+	TextWidth(text string, small bool) int
+}
+
+// FixedMetrics measures every rune with CharWidth and East Asian wide runes
+// with twice the CharWidth, independent of the font size.
+// It is the default for flows built in Go, so their diagrams don't change
+// with the font of the theme. Texts are squeezed into their fixed width.
+// Flow files and the flowdoc command use FontMetrics by default.
+type FixedMetrics struct{}
+
+// TextWidth implements TextMetrics.
+func (FixedMetrics) TextWidth(text string, _ bool) int {
+	width := 0
+	for _, r := range text {
+		width += runeUnits(r, CharWidth, 2*CharWidth)
+	}
+	return width
+}
+
+// FontMetrics measures texts with the advance widths of the default
+// sans-serif font of most SVG viewers (Helvetica, Arial and metric
+// compatible fonts) or of a monospace font (Courier, DejaVu Sans Mono, ...).
+// Other proportional fonts are measured like the sans-serif font.
+// East Asian wide runes and emojis are one em wide and combining marks
+// don't have a width at all.
+// A zero FontMetrics uses the font family and sizes of the theme of the
+// flow.
+// Texts measured with any metrics but FixedMetrics are drawn with their
+// natural width instead of being squeezed with textLength.
+type FontMetrics struct {
+	FontSize      int
+	SmallFontSize int
+	Monospace     bool
+}
+
+// NewFontMetrics returns font metrics for the font family and sizes of the
+// given theme. If theme is nil, the LightTheme is used.
+func NewFontMetrics(theme *Theme) FontMetrics {
+	theme = themeOrDefault(theme)
+	return FontMetrics{
+		FontSize:      theme.FontSize,
+		SmallFontSize: theme.SmallFontSize,
+		Monospace:     isMonospace(theme.FontFamily),
+	}
+}
+
+// TextWidth implements TextMetrics.
+func (fm FontMetrics) TextWidth(text string, small bool) int {
+	size := fm.FontSize
+	if small {
+		size = fm.SmallFontSize
+	}
+	units := 0
+	if fm.Monospace {
+		for _, r := range text {
+			units += runeUnits(r, monoUnits, emUnits)
+		}
+	} else {
+		units = fontUnits(text)
+	}
+	return (units*size + emUnits - 1) / emUnits // round up, so texts never overflow
+}
+
+// fontUnits returns the advance width of text in 1/1000 em.
+func fontUnits(text string) int {
+	units := 0
+	for _, r := range text {
+		if r >= ' ' && r <= '~' {
+			units += asciiUnits[r-' ']
+		} else {
+			units += runeUnits(r, defaultUnits, emUnits)
+		}
+	}
+	return units
+}
+
+const (
+	emUnits      = 1000
+	defaultUnits = 556 // width of digits and most lower case letters
+	monoUnits    = 602 // width of all runes of common monospace fonts
+)
+
+// monoFonts contains parts of the names of common monospace fonts.
+var monoFonts = []string{"mono", "courier", "consolas", "menlo", "monaco", "fixed"}
+
+// isMonospace returns true if the first font of the CSS font family is a
+// monospace font. The viewer uses the other fonts only if it lacks the
+// first one.
+func isMonospace(family string) bool {
+	first, _, _ := strings.Cut(family, ",")
+	first = strings.ToLower(strings.Trim(strings.TrimSpace(first), `"'`))
+	for _, mono := range monoFonts {
+		if strings.Contains(first, mono) {
+			return true
+		}
+	}
+	return false
+}
+
+// asciiUnits contains the advance widths of the printable ASCII runes
+// (from ' ' to '~') in 1/1000 em.
+var asciiUnits = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // ' ' - '/'
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // '0' - '?'
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // '@' - 'O'
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // 'P' - '_'
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // '`' - 'o'
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // 'p' - '~'
+}
+
+// runeUnits returns 0 for combining marks and other invisible runes,
+// wide for East Asian wide runes and emojis and normal otherwise.
+func runeUnits(r rune, normal, wide int) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc):
+		return 0
+	case isWide(r):
+		return wide
+	default:
+		return normal
+	}
+}
+
+// wideRanges contains the East Asian wide and fullwidth ranges plus the
+// emoji ranges of Unicode.
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x231A, 0x231B},   // watch, hourglass
+	{0x2329, 0x232A},   // angle brackets
+	{0x23E9, 0x23EC},   // media controls
+	{0x23F0, 0x23F0},   // alarm clock
+	{0x23F3, 0x23F3},   // hourglass
+	{0x25FD, 0x25FE},   // small squares
+	{0x2614, 0x2615},   // umbrella, hot beverage
+	{0x2648, 0x2653},   // zodiac
+	{0x26A1, 0x26A1},   // high voltage
+	{0x26AA, 0x26AB},   // circles
+	{0x26BD, 0x26BE},   // soccer, baseball
+	{0x26C4, 0x26C5},   // snowman, sun
+	{0x26D4, 0x26D4},   // no entry
+	{0x26EA, 0x26EA},   // church
+	{0x26F2, 0x26F5},   // fountain ... sailboat
+	{0x26FA, 0x26FD},   // tent ... fuel pump
+	{0x2705, 0x2705},   // check mark
+	{0x270A, 0x270B},   // fists
+	{0x2728, 0x2728},   // sparkles
+	{0x274C, 0x274C},   // cross mark
+	{0x2753, 0x2755},   // question marks
+	{0x2757, 0x2757},   // exclamation mark
+	{0x2795, 0x2797},   // plus, minus, division
+	{0x27B0, 0x27B0},   // curly loop
+	{0x27BF, 0x27BF},   // double curly loop
+	{0x2B1B, 0x2B1C},   // large squares
+	{0x2B50, 0x2B50},   // star
+	{0x2B55, 0x2B55},   // circle
+	{0x2E80, 0x303E},   // CJK radicals ... CJK symbols and punctuation
+	{0x3041, 0x33FF},   // Hiragana ... CJK compatibility
+	{0x3400, 0x4DBF},   // CJK unified ideographs extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xA960, 0xA97F},   // Hangul Jamo extended A
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE10, 0xFE19},   // vertical forms
+	{0xFE30, 0xFE6F},   // CJK compatibility forms, small form variants
+	{0xFF00, 0xFF60},   // fullwidth forms
+	{0xFFE0, 0xFFE6},   // fullwidth signs
+	{0x16FE0, 0x18CFF}, // Tangut ...
+	{0x1B000, 0x1B2FF}, // Kana supplement ... Nushu
+	{0x1F004, 0x1F004}, // mahjong tile
+	{0x1F0CF, 0x1F0CF}, // playing card
+	{0x1F18E, 0x1F18E}, // AB button
+	{0x1F191, 0x1F19A}, // squared words
+	{0x1F200, 0x1F2FF}, // enclosed ideographic supplement
+	{0x1F300, 0x1F64F}, // pictographs and emoticons
+	{0x1F680, 0x1F6FF}, // transport and map symbols
+	{0x1F7E0, 0x1F7EB}, // colored circles and squares
+	{0x1F90C, 0x1F9FF}, // supplemental symbols and pictographs
+	{0x1FA70, 0x1FAFF}, // symbols and pictographs extended A
+	{0x20000, 0x3FFFD}, // CJK unified ideographs extension B ...
+}
+
+func isWide(r rune) bool {
+	if r < wideRanges[0].lo {
+		return false
+	}
+	for _, wr := range wideRanges {
+		if r < wr.lo {
+			return false
+		}
+		if r <= wr.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// measured returns true if tm measures the real widths of texts instead of
+// using a fixed width per character.
+func measured(tm TextMetrics) bool {
+	_, fixed := tm.(FixedMetrics)
+	return tm != nil && !fixed
+}
+
+// textWidth measures text with the given metrics or with FixedMetrics if
+// tm is nil.
+func textWidth(tm TextMetrics, text string, small bool) int {
+	if tm == nil {
+		return FixedMetrics{}.TextWidth(text, small)
+	}
+	return tm.TextWidth(text, small)
+}
@@ -0,0 +1,135 @@
+package draw_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestTextMetrics(t *testing.T) {
+	specs := []struct {
+		name          string
+		givenMetrics  draw.TextMetrics
+		givenText     string
+		givenSmall    bool
+		expectedWidth int
+	}{
+		{
+			name:          "fixed-ascii",
+			givenMetrics:  draw.FixedMetrics{},
+			givenText:     "Parser",
+			expectedWidth: 6 * draw.CharWidth,
+		}, {
+			name:          "fixed-small",
+			givenMetrics:  draw.FixedMetrics{},
+			givenText:     "out",
+			givenSmall:    true,
+			expectedWidth: 3 * draw.CharWidth,
+		}, {
+			name:          "fixed-wide",
+			givenMetrics:  draw.FixedMetrics{},
+			givenText:     "解析器",
+			expectedWidth: 6 * draw.CharWidth,
+		}, {
+			name:          "fixed-emoji",
+			givenMetrics:  draw.FixedMetrics{},
+			givenText:     "a🚀",
+			expectedWidth: 3 * draw.CharWidth,
+		}, {
+			name:          "fixed-combining",
+			givenMetrics:  draw.FixedMetrics{},
+			givenText:     "é",
+			expectedWidth: draw.CharWidth,
+		}, {
+			name:          "font-narrow",
+			givenMetrics:  draw.FontMetrics{FontSize: 16, SmallFontSize: 14},
+			givenText:     "ii",
+			expectedWidth: 8, // 2 * 0.222 * 16 = 7.1
+		}, {
+			name:          "font-wide",
+			givenMetrics:  draw.FontMetrics{FontSize: 16, SmallFontSize: 14},
+			givenText:     "WW",
+			expectedWidth: 31, // 2 * 0.944 * 16 = 30.2
+		}, {
+			name:          "font-small",
+			givenMetrics:  draw.FontMetrics{FontSize: 16, SmallFontSize: 14},
+			givenText:     "WW",
+			givenSmall:    true,
+			expectedWidth: 27, // 2 * 0.944 * 14 = 26.4
+		}, {
+			name:          "font-cjk",
+			givenMetrics:  draw.FontMetrics{FontSize: 16, SmallFontSize: 14},
+			givenText:     "解析器",
+			expectedWidth: 48,
+		}, {
+			name:          "font-combining",
+			givenMetrics:  draw.FontMetrics{FontSize: 16, SmallFontSize: 14},
+			givenText:     "ä̈",
+			expectedWidth: 9, // 0.556 * 16 = 8.9
+		}, {
+			name:          "font-mono",
+			givenMetrics:  draw.FontMetrics{FontSize: 16, SmallFontSize: 14, Monospace: true},
+			givenText:     "WWii",
+			expectedWidth: 39, // 4 * 0.602 * 16 = 38.5
+		}, {
+			name:          "font-mono-theme",
+			givenMetrics:  draw.NewFontMetrics(monoTheme()),
+			givenText:     "WWii",
+			expectedWidth: 39,
+		}, {
+			name:          "font-sans-theme",
+			givenMetrics:  draw.NewFontMetrics(&draw.Theme{FontFamily: "Arial, monospace", FontSize: 16}),
+			givenText:     "WWii",
+			expectedWidth: 38, // 2 * (0.944 + 0.222) * 16 = 37.3
+		}, {
+			name:          "empty",
+			givenMetrics:  draw.NewFontMetrics(nil),
+			givenText:     "",
+			expectedWidth: 0,
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			actualWidth := spec.givenMetrics.TextWidth(spec.givenText, spec.givenSmall)
+			if actualWidth != spec.expectedWidth {
+				t.Errorf("expected width %d, got: %d", spec.expectedWidth, actualWidth)
+			}
+		})
+	}
+}
+
+func monoTheme() *draw.Theme {
+	theme := draw.LightTheme()
+	theme.FontFamily = `"DejaVu Sans Mono", monospace`
+	return theme
+}
+
+func TestDrawWithFontMetrics(t *testing.T) {
+	flow := draw.NewFlow("metricsFlow", draw.FlowModeNoLinks, 1500, nil)
+	flow.SetTextMetrics(draw.FontMetrics{}) // font sizes of the theme
+	flow.AddStart(
+		draw.NewStartPort("in").AddOutput(
+			draw.NewArrow("", "").AddDestination(
+				draw.NewComp("", "WWWW", "", flow).AddOutput(
+					draw.NewArrow("", "").AddDestination(draw.NewEndPort("out")),
+				),
+			),
+		),
+	)
+
+	svgs, _, err := flow.Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svg := string(svgs["flowdev/flow-metricsFlow.svg"])
+	for _, expected := range []string{
+		`width="73" height="22" x="26"`, // 61 for the text and the padding
+		`x="32" y="18">WWWW<`,           // measured texts aren't squeezed
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("expected SVG to contain %q, got:\n%s", expected, svg)
+		}
+	}
+}
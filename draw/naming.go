@@ -0,0 +1,84 @@
+package draw
+
+import (
+	"fmt"
+	"path"
+)
+
+// Naming decides the names of the diagram files of a flow.
+// The names are slash separated paths relative to the MarkDown file of the
+// flow. They are used as keys of the diagram contents returned by Draw and
+// as links in the MarkDown file.
+// All names end in ".svg". It is replaced for other formats.
+type Naming interface {
+	// Diagram returns the name of the single diagram of the flow.
+	Diagram(flow string) string
+	// Tile returns the name of a small diagram in FlowModeMDLinks.
+	// idx is the index of the tile in its line and comp is the name of the
+	// component shown in it (if any).
+	Tile(flow string, idx, line int, comp string) string
+	// Filler returns the name of an empty diagram that fills up a line in
+	// FlowModeMDLinks.
+	Filler(flow string, width, height int) string
+	// SharedTile returns the name of a tile that is named by the hash of
+	// its content (see UseSharedTiles).
+	SharedTile(hash string) string
+}
+
+// PrefixNaming puts all diagram files into the directory Dir and prefixes
+// them with Prefix and the name of the flow.
+// Shared tiles are put into TileDir or Dir if TileDir is empty.
+type PrefixNaming struct {
+	Dir     string
+	Prefix  string
+	TileDir string
+}
+
+// DefaultNaming puts the diagram files into the directory 'flowdev' next
+// to the MarkDown file and prefixes them with 'flow-'.
+var DefaultNaming Naming = PrefixNaming{Dir: "flowdev", Prefix: "flow-"}
+
+func (n PrefixNaming) base(flow string) string {
+	return path.Join(n.Dir, n.Prefix+flow)
+}
+
+// Diagram implements the Naming interface.
+func (n PrefixNaming) Diagram(flow string) string {
+	return n.base(flow) + ".svg"
+}
+
+// Tile implements the Naming interface.
+func (n PrefixNaming) Tile(flow string, idx, line int, comp string) string {
+	if comp == "" {
+		return fmt.Sprintf("%s-%d-%d.svg", n.base(flow), idx, line)
+	}
+	return fmt.Sprintf("%s-%d-%d-%s.svg", n.base(flow), idx, line, comp)
+}
+
+// Filler implements the Naming interface.
+func (n PrefixNaming) Filler(flow string, width, height int) string {
+	return fmt.Sprintf("%s-filler-%d-%d.svg", n.base(flow), width, height)
+}
+
+// SharedTile implements the Naming interface.
+func (n PrefixNaming) SharedTile(hash string) string {
+	dir := n.TileDir
+	if dir == "" {
+		dir = n.Dir
+	}
+	return path.Join(dir, "tile-"+hash+".svg")
+}
+
+// SetNaming sets the naming of the diagram files.
+// If naming is nil, the DefaultNaming is used.
+func (flow *Flow) SetNaming(naming Naming) *Flow {
+	flow.naming = naming
+	return flow
+}
+
+func (flow *Flow) namingOrDefault() Naming {
+	if flow.naming == nil {
+		return DefaultNaming
+	}
+	return flow.naming
+}
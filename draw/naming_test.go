@@ -0,0 +1,47 @@
+package draw_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestDrawNaming(t *testing.T) {
+	naming := draw.PrefixNaming{Dir: "../img/orders", Prefix: "orders-"}
+
+	flow := buildSmallTestFlow().SetNaming(naming)
+	files, md, err := flow.Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := files["../img/orders/orders-smallTestFlow.svg"]; !ok || len(files) != 1 {
+		t.Errorf("expected a single diagram with the custom name, got: %q", keys(files))
+	}
+	if !strings.Contains(string(md), "(../img/orders/orders-smallTestFlow.svg)") {
+		t.Errorf("expected a link to the diagram with the custom name, got:\n%s", md)
+	}
+
+	flow = buildSmallTestFlow().SetNaming(naming)
+	flow.ChangeConfig("smallTestFlow", draw.FlowModeMDLinks, 1500, nil)
+	files, md, err = flow.Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name := range files {
+		if !strings.HasPrefix(name, "../img/orders/orders-smallTestFlow-") {
+			t.Errorf("expected all tiles to have the custom prefix, got: %q", name)
+		}
+		if !strings.Contains(string(md), "("+name+")") {
+			t.Errorf("expected a link to tile %q, got:\n%s", name, md)
+		}
+	}
+}
+
+func keys(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	return names
+}
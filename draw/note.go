@@ -0,0 +1,138 @@
+package draw
+
+const (
+	groupPadding   = 4 // space between a group box and its components
+	groupTextSpace = LineHeight - TextOffset
+	groupDash      = 6 // length of the dashes of a group box
+	groupGap       = 3 // length of the gaps between the dashes
+)
+
+// AddNote adds a line of free text at the bottom of the component.
+func (comp *Comp) AddNote(text string) *Comp {
+	comp.notes = append(comp.notes, text)
+	return comp
+}
+
+// AddNote adds a line of free text above the data types of the arrow.
+func (arr *Arrow) AddNote(text string) *Arrow {
+	arr.notes = append(arr.notes, text)
+	return arr
+}
+
+// Group is a labeled box drawn around a set of components, e.g. all
+// components of a bounded context or package.
+// Groups are only drawn in single diagrams and not in the many small
+// diagrams of FlowModeMDLinks.
+type Group struct {
+	label string
+	comps []*Comp
+}
+
+// NewGroup creates a new empty group with the given label.
+func NewGroup(label string) *Group {
+	return &Group{label: label}
+}
+
+// AddComp adds a component to the group.
+func (g *Group) AddComp(comp *Comp) *Group {
+	g.comps = append(g.comps, comp)
+	return g
+}
+
+// AddGroup adds a group box to the flow.
+// All its components have to be part of the flow.
+func (flow *Flow) AddGroup(g *Group) *Flow {
+	flow.groups = append(flow.groups, g)
+	return flow
+}
+
+// notesWidth returns the width needed for the notes.
+func notesWidth(tm TextMetrics, notes []string) int {
+	width := 0
+	for _, note := range notes {
+		width = max(width, WordGap+textWidth(tm, note, true)+WordGap)
+	}
+	return width
+}
+
+func noteToSVG(svg *svgFlow, tm TextMetrics, x0, y0 int, note string) {
+	svg.Texts = append(svg.Texts, &svgText{
+		X:      x0 + WordGap,
+		Y:      y0 + LineHeight - TextOffset,
+		Width:  textWidth(tm, note, true),
+		Text:   note,
+		Small:  true,
+		Italic: true,
+	})
+}
+
+// contentLines returns the number of lines of the component without its
+// notes.
+func (comp *Comp) contentLines() int {
+	lines := 1
+	if comp.name != "" {
+		lines++
+	}
+	for _, p := range comp.plugins {
+		if p.title != "" {
+			lines++
+		}
+		lines += len(p.types)
+	}
+	return lines
+}
+
+// notesToSVG adds the note of the component that is in the line.
+// It returns false if no note is in the line.
+func (comp *Comp) notesToSVG(svg *svgFlow, line int) bool {
+	cd := comp.drawData
+	idx := line - cd.minLine - comp.contentLines()
+	if idx < 0 || idx >= len(comp.notes) {
+		return false
+	}
+	left, _ := kindPadding(comp.kind)
+	noteToSVG(svg, comp.metrics, cd.x0+left, cd.y0+(line-cd.minLine)*LineHeight, comp.notes[idx])
+	return true
+}
+
+// groupsToSVG adds the boxes of all groups to the SVG flow.
+// The view box is extended if the boxes don't fit into it.
+func (flow *Flow) groupsToSVG(svg *svgFlow) {
+	for _, g := range flow.groups {
+		box := &svgGroup{Label: g.label}
+		first := true
+		for _, src := range g.comps {
+			comp := flow.copies[src]
+			if comp == nil || comp.drawData == nil {
+				continue
+			}
+			cd := comp.drawData
+			if first {
+				box.X, box.Y, box.Width, box.Height = cd.x0, cd.y0, cd.width, cd.height
+				first = false
+				continue
+			}
+			x1, y1 := max(box.X+box.Width, cd.xmax()), max(box.Y+box.Height, cd.ymax())
+			box.X, box.Y = min(box.X, cd.x0), min(box.Y, cd.y0)
+			box.Width, box.Height = x1-box.X, y1-box.Y
+		}
+		if first {
+			continue
+		}
+		box.X -= groupPadding
+		box.Y -= groupPadding
+		box.Width += 2 * groupPadding
+		box.Height += 2 * groupPadding
+		box.TextX = box.X + WordGap
+		box.TextY = box.Y - TextOffset/2
+		box.TextWidth = textWidth(flow.textMetrics(), g.label, true)
+		svg.Groups = append(svg.Groups, box)
+
+		// make room for the box and its label:
+		x0 := min(svg.X0, box.X)
+		y0 := min(svg.Y0, box.Y-groupTextSpace)
+		x1 := max(svg.X0+svg.TotalWidth, box.X+box.Width+1, box.TextX+box.TextWidth)
+		y1 := max(svg.Y0+svg.TotalHeight, box.Y+box.Height+1)
+		svg.X0, svg.Y0, svg.TotalWidth, svg.TotalHeight = x0, y0, x1-x0, y1-y0
+	}
+}
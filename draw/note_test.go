@@ -0,0 +1,46 @@
+package draw_test
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestDrawNotesPNG(t *testing.T) {
+	pi := drawPNGImage(t, archiveFlow(t, "note.txt", "notes.json"), 0, -22, 342, 99)
+
+	background := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	text := color.RGBA{A: 255}
+	// the bottom edge of the group is drawn from right to left:
+	pi.checkColor(t, "dash of group", 306, 76, text)
+	pi.checkColor(t, "gap of group", 301.5, 76, background)
+}
+
+func TestDrawNotesPDF(t *testing.T) {
+	pdf := drawFile(t, archiveFlow(t, "note.txt", "notes.json"), draw.FormatPDF)
+
+	for _, expected := range []string{
+		"\n0 0 0 RG 1 w [6 3] 0 d\n32 -4 m\n", // group with rounded corners
+		"(billing) Tj",
+		"(valid only) Tj",
+		"(with retries) Tj",
+	} {
+		if !bytes.Contains(pdf, []byte(expected)) {
+			t.Errorf("expected PDF to contain %q, got:\n%s", expected, pdf)
+		}
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	flow := archiveFlow(t, "note.txt", "notes.json")
+	other := draw.NewFlow("other", draw.FlowModeNoLinks, 1500, nil)
+	flow.AddGroup(draw.NewGroup("lost").AddComp(draw.NewComp("lost", "", "", other)))
+
+	var unreached *draw.UnreachableError
+	if err := flow.Validate(); !errors.As(err, &unreached) || unreached.ID != "lost" {
+		t.Errorf("expected unreachable group member, got: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package draw
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// bezierCircle is the distance of the control points of a Bézier curve
+// approximating a quarter circle with radius 1.
+const bezierCircle = 0.5523
+
+// svgFlowToPDF creates a single page PDF document with the shapes of the SVG
+// flow. Texts use the standard Helvetica font of the PDF viewer and are
+// scaled horizontally to their width.
+func svgFlowToPDF(sf *svgFlow, theme *Theme) ([]byte, error) {
+	colors, err := parseThemeColors(theme)
+	if err != nil {
+		return nil, err
+	}
+	ps := &pdfStream{}
+	// use the coordinates of the SVG with the y axis pointing down:
+	ps.printf("1 0 0 -1 %s %s cm\n", pdfNum(-float64(sf.X0)), pdfNum(float64(sf.TotalHeight+sf.Y0)))
+	ps.printf("1 J 1 j\n")
+	ps.printf("%s rg %d %d %d %d re f\n", pdfColor(colors.background), sf.X0, sf.Y0, sf.TotalWidth, sf.TotalHeight)
+
+	for _, g := range sf.Groups {
+		ps.printf("%s RG %d w [%d %d] 0 d\n", pdfColor(colors.text), theme.ThinStrokeWidth, groupDash, groupGap)
+		ps.roundRect(float64(g.X), float64(g.Y), float64(g.Width), float64(g.Height), float64(theme.CornerRadius))
+		ps.printf("S [] 0 d\n")
+		ps.text(&svgText{X: g.TextX, Y: g.TextY, Width: g.TextWidth, Text: g.Label}, theme.SmallFontSize, colors.text)
+	}
+	ps.printf("%s RG %d w\n", pdfColor(colors.text), theme.StrokeWidth)
+	for _, arr := range sf.Arrows {
+		if arr.Error {
+			ps.printf("q %s RG [%d %d] 0 d\n", pdfColor(arr.Change.strokeColor(colors, colors.error)), groupDash, groupGap)
+		} else if arr.Change != Unchanged {
+			ps.printf("q %s RG\n", pdfColor(arr.Change.strokeColor(colors, colors.text)))
+		}
+		ps.printf("%d %d m %d %d l S\n", arr.X1, arr.Y1, arr.X2, arr.Y2)
+		if arr.Error {
+			ps.printf("[] 0 d\n")
+		}
+		ps.printf("%d %d m %d %d l %d %d l S\n", arr.XTip1, arr.YTip1, arr.X2, arr.Y2, arr.XTip2, arr.YTip2)
+		if arr.Error || arr.Change != Unchanged {
+			ps.printf("Q\n")
+		}
+	}
+	for _, path := range sf.Paths {
+		if path.Error {
+			ps.printf("q %s RG [%d %d] 0 d\n", pdfColor(path.Change.strokeColor(colors, colors.error)), groupDash, groupGap)
+		} else if path.Change != Unchanged {
+			ps.printf("q %s RG\n", pdfColor(path.Change.strokeColor(colors, colors.text)))
+		}
+		ps.polyline(path.Points)
+		ps.printf("S\n")
+		if path.Error {
+			ps.printf("[] 0 d\n")
+		}
+		if path.Tip {
+			ps.printf("%d %d m %d %d l %d %d l S\n", path.XTip1, path.YTip1, path.X2, path.Y2, path.XTip2, path.YTip2)
+		}
+		if path.Error || path.Change != Unchanged {
+			ps.printf("Q\n")
+		}
+	}
+	for _, rect := range sf.Rects {
+		fill, width := colors.comp, theme.StrokeWidth
+		switch {
+		case rect.SubRect:
+			fill, width = colors.pluginType, theme.ThinStrokeWidth
+		case rect.Plugin:
+			fill = colors.plugin
+		}
+		if rect.Change != Unchanged {
+			ps.printf("q %s RG\n", pdfColor(rect.Change.strokeColor(colors, colors.text)))
+		}
+		ps.printf("%s rg %d w\n", pdfColor(fill), width)
+		if outline := rect.Outline(); outline != nil {
+			ps.polyline(outline)
+			ps.printf("h ")
+		} else {
+			ps.roundRect(float64(rect.X), float64(rect.Y), float64(rect.Width), float64(rect.Height),
+				float64(cmp.Or(rect.Radius(), theme.CornerRadius)))
+		}
+		if width > 0 {
+			ps.printf("B\n")
+		} else {
+			ps.printf("f\n")
+		}
+		for _, mark := range rect.Marks() {
+			ps.polyline(mark)
+			ps.printf("S\n")
+		}
+		if rect.Change != Unchanged {
+			ps.printf("Q\n")
+		}
+	}
+	for _, m := range sf.Markers {
+		r := float64(m.Radius)
+		ps.printf("%s rg\n", pdfColor(colors.error))
+		ps.roundRect(float64(m.X)-r, float64(m.Y)-r, 2*r, 2*r, r)
+		ps.printf("f\n")
+		ps.text(&svgText{X: m.TextX(), Y: m.TextY(), Width: m.TextWidth(), Text: "!"}, theme.SmallFontSize, colors.background)
+	}
+	for _, txt := range sf.Texts {
+		size, col := theme.FontSize, colors.text
+		if txt.Small {
+			size = theme.SmallFontSize
+		}
+		switch {
+		case txt.GoLink:
+			col = colors.goLink
+		case txt.Link:
+			col = colors.link
+		case txt.Error:
+			col = colors.error
+		}
+		ps.text(txt, size, col)
+	}
+	return pdfDocument(sf.TotalWidth, sf.TotalHeight, ps.buf.Bytes()), nil
+}
+
+// pdfStream is the content stream of a PDF page.
+type pdfStream struct {
+	buf bytes.Buffer
+}
+
+func (ps *pdfStream) printf(format string, args ...any) {
+	fmt.Fprintf(&ps.buf, format, args...)
+}
+
+// text shows the text scaled horizontally to its width.
+func (ps *pdfStream) text(txt *svgText, size int, col color.RGBA) {
+	units := fontUnits(txt.Text)
+	if units == 0 {
+		return
+	}
+	scale := float64(txt.Width) * emUnits * 100 / float64(units*size)
+	ps.printf("BT %s rg /F1 %d Tf %s Tz 1 0 0 -1 %d %d Tm (%s) Tj ET\n",
+		pdfColor(col), size, pdfNum(scale), txt.X, txt.Y, pdfString(txt.Text))
+}
+
+// polyline adds the connected points to the current path.
+func (ps *pdfStream) polyline(points []svgPoint) {
+	for i, p := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		ps.printf("%d %d %s ", p.X, p.Y, op)
+	}
+}
+
+// roundRect adds a rectangle with rounded corners to the current path.
+func (ps *pdfStream) roundRect(x, y, w, h, r float64) {
+	r = min(r, w/2, h/2)
+	k := r * bezierCircle
+	x1, y1 := x+w, y+h
+	n := pdfNum
+	ps.printf("%s %s m\n", n(x+r), n(y))
+	ps.printf("%s %s l %s %s %s %s %s %s c\n", n(x1-r), n(y), n(x1-r+k), n(y), n(x1), n(y+r-k), n(x1), n(y+r))
+	ps.printf("%s %s l %s %s %s %s %s %s c\n", n(x1), n(y1-r), n(x1), n(y1-r+k), n(x1-r+k), n(y1), n(x1-r), n(y1))
+	ps.printf("%s %s l %s %s %s %s %s %s c\n", n(x+r), n(y1), n(x+r-k), n(y1), n(x), n(y1-r+k), n(x), n(y1-r))
+	ps.printf("%s %s l %s %s %s %s %s %s c h\n", n(x), n(y+r), n(x), n(y+r-k), n(x+r-k), n(y), n(x+r), n(y))
+}
+
+// pdfDocument wraps the content stream of a single page in a PDF document.
+func pdfDocument(width, height int, content []byte) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>", width, height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func pdfColor(col color.RGBA) string {
+	return pdfNum(float64(col.R)/255) + " " + pdfNum(float64(col.G)/255) + " " + pdfNum(float64(col.B)/255)
+}
+
+// pdfNum formats a number with at most 3 decimals.
+func pdfNum(f float64) string {
+	s := strings.TrimRight(strconv.FormatFloat(f, 'f', 3, 64), "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// pdfString returns the text as the content of a PDF string in
+// WinAnsiEncoding. Runes that can't be encoded are replaced with '?'.
+func pdfString(text string) string {
+	sb := strings.Builder{}
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '…':
+			sb.WriteString(`\205`)
+		case r >= ' ' && r <= '~':
+			sb.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&sb, `\%03o`, r)
+		case runeUnits(r, 1, 1) == 0:
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
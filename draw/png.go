@@ -0,0 +1,365 @@
+package draw
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	pngScale     = 2    // pixels of the PNG per pixel of the SVG
+	capHeightEms = 0.72 // height of capital letters relative to the font size
+)
+
+// svgFlowToPNG rasterizes the shapes of the SVG flow to a PNG image.
+// Texts are drawn with a built-in bitmap font that is stretched to the width
+// of the text.
+func svgFlowToPNG(sf *svgFlow, theme *Theme) ([]byte, error) {
+	colors, err := parseThemeColors(theme)
+	if err != nil {
+		return nil, err
+	}
+	c := &canvas{
+		img: image.NewRGBA(image.Rect(0, 0, sf.TotalWidth*pngScale, sf.TotalHeight*pngScale)),
+		x0:  float64(sf.X0),
+		y0:  float64(sf.Y0),
+	}
+	c.rect(float64(sf.X0), float64(sf.Y0), float64(sf.TotalWidth), float64(sf.TotalHeight), 0,
+		colors.background, colors.background, 0)
+
+	sw := float64(theme.StrokeWidth)
+	for _, g := range sf.Groups {
+		x0, y0, x1, y1 := g.X, g.Y, g.X+g.Width, g.Y+g.Height
+		for _, edge := range [][4]int{{x0, y0, x1, y0}, {x1, y0, x1, y1}, {x1, y1, x0, y1}, {x0, y1, x0, y0}} {
+			c.dashedLine(edge[0], edge[1], edge[2], edge[3], float64(theme.ThinStrokeWidth), colors.text)
+		}
+		c.text(&svgText{X: g.TextX, Y: g.TextY, Width: g.TextWidth, Text: g.Label}, float64(theme.SmallFontSize), colors.text)
+	}
+	for _, arr := range sf.Arrows {
+		col := colors.text
+		if arr.Error {
+			col = arr.Change.strokeColor(colors, colors.error)
+			c.dashedLine(arr.X1, arr.Y1, arr.X2, arr.Y2, sw, col)
+		} else {
+			col = arr.Change.strokeColor(colors, col)
+			c.line(arr.X1, arr.Y1, arr.X2, arr.Y2, sw, col)
+		}
+		c.line(arr.XTip1, arr.YTip1, arr.X2, arr.Y2, sw, col)
+		c.line(arr.XTip2, arr.YTip2, arr.X2, arr.Y2, sw, col)
+	}
+	for _, path := range sf.Paths {
+		col := colors.text
+		if path.Error {
+			col = colors.error
+		}
+		col = path.Change.strokeColor(colors, col)
+		for i := 1; i < len(path.Points); i++ {
+			p0, p1 := path.Points[i-1], path.Points[i]
+			if path.Error {
+				c.dashedLine(p0.X, p0.Y, p1.X, p1.Y, sw, col)
+			} else {
+				c.line(p0.X, p0.Y, p1.X, p1.Y, sw, col)
+			}
+		}
+		if path.Tip {
+			c.line(path.XTip1, path.YTip1, path.X2, path.Y2, sw, col)
+			c.line(path.XTip2, path.YTip2, path.X2, path.Y2, sw, col)
+		}
+	}
+	for _, rect := range sf.Rects {
+		fill, width := colors.comp, sw
+		switch {
+		case rect.SubRect:
+			fill, width = colors.pluginType, float64(theme.ThinStrokeWidth)
+		case rect.Plugin:
+			fill = colors.plugin
+		}
+		stroke := rect.Change.strokeColor(colors, colors.text)
+		if outline := rect.Outline(); outline != nil {
+			c.polygon(outline, fill, stroke, width)
+		} else {
+			c.rect(float64(rect.X), float64(rect.Y), float64(rect.Width), float64(rect.Height),
+				float64(cmp.Or(rect.Radius(), theme.CornerRadius)), fill, stroke, width)
+		}
+		for _, mark := range rect.Marks() {
+			for i := 1; i < len(mark); i++ {
+				c.line(mark[i-1].X, mark[i-1].Y, mark[i].X, mark[i].Y, width, colors.text)
+			}
+		}
+	}
+	for _, m := range sf.Markers {
+		r := float64(m.Radius)
+		c.rect(float64(m.X)-r, float64(m.Y)-r, 2*r, 2*r, r, colors.error, colors.error, 0)
+		c.text(&svgText{X: m.TextX(), Y: m.TextY(), Width: m.TextWidth(), Text: "!"}, float64(theme.SmallFontSize), colors.background)
+	}
+	for _, txt := range sf.Texts {
+		size, col := theme.FontSize, colors.text
+		if txt.Small {
+			size = theme.SmallFontSize
+		}
+		switch {
+		case txt.GoLink:
+			col = colors.goLink
+		case txt.Link:
+			col = colors.link
+		case txt.Error:
+			col = colors.error
+		}
+		c.text(txt, float64(size), col)
+	}
+
+	buf := bytes.Buffer{}
+	if err = png.Encode(&buf, c.img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// themeColors contains the parsed colors of a theme.
+type themeColors struct {
+	background, text, link, goLink, comp, plugin, pluginType, error color.RGBA
+}
+
+func parseThemeColors(theme *Theme) (*themeColors, error) {
+	tc := &themeColors{}
+	values := []string{theme.Background, theme.Text, theme.Link, theme.GoLink, theme.Comp, theme.Plugin, theme.PluginType, theme.Error}
+	colors := []*color.RGBA{&tc.background, &tc.text, &tc.link, &tc.goLink, &tc.comp, &tc.plugin, &tc.pluginType, &tc.error}
+	for i, value := range values {
+		col, err := parseColor(value)
+		if err != nil {
+			return nil, err
+		}
+		*colors[i] = col
+	}
+	return tc, nil
+}
+
+// parseColor parses colors of the form 'rgb(r,g,b)', '#rrggbb' and '#rgb'.
+func parseColor(s string) (color.RGBA, error) {
+	s = strings.TrimSpace(s)
+	var parts []string
+	base := 10
+	switch {
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		parts = strings.Split(s[4:len(s)-1], ",")
+	case strings.HasPrefix(s, "#") && len(s) == 7:
+		parts = []string{s[1:3], s[3:5], s[5:7]}
+		base = 16
+	case strings.HasPrefix(s, "#") && len(s) == 4:
+		parts = []string{s[1:2] + s[1:2], s[2:3] + s[2:3], s[3:4] + s[3:4]}
+		base = 16
+	}
+	if len(parts) != 3 {
+		return color.RGBA{}, fmt.Errorf("unsupported color %q (expected 'rgb(r,g,b)', '#rrggbb' or '#rgb')", s)
+	}
+	var rgb [3]uint8
+	for i, part := range parts {
+		v, err := strconv.ParseUint(strings.TrimSpace(part), base, 8)
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("unsupported color %q: %w", s, err)
+		}
+		rgb[i] = uint8(v)
+	}
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}, nil
+}
+
+// canvas draws anti-aliased shapes in SVG coordinates onto an image.
+type canvas struct {
+	img    *image.RGBA
+	x0, y0 float64 // origin of the SVG view box
+}
+
+// toImage converts SVG coordinates to image coordinates.
+func (c *canvas) toImage(x, y float64) (float64, float64) {
+	return (x - c.x0) * pngScale, (y - c.y0) * pngScale
+}
+
+// blend paints col over the pixel with the given coverage (0 to 1).
+func (c *canvas) blend(x, y int, col color.RGBA, coverage float64) {
+	if coverage <= 0 || !(image.Point{X: x, Y: y}).In(c.img.Rect) {
+		return
+	}
+	coverage = min(coverage, 1)
+	dst := c.img.RGBAAt(x, y)
+	mix := func(d, s uint8) uint8 {
+		return uint8(math.Round(float64(d)*(1-coverage) + float64(s)*coverage))
+	}
+	c.img.SetRGBA(x, y, color.RGBA{
+		R: mix(dst.R, col.R),
+		G: mix(dst.G, col.G),
+		B: mix(dst.B, col.B),
+		A: mix(dst.A, 255),
+	})
+}
+
+// line draws a straight line with round ends.
+func (c *canvas) line(x1, y1, x2, y2 int, width float64, col color.RGBA) {
+	if width <= 0 {
+		return
+	}
+	ax, ay := c.toImage(float64(x1), float64(y1))
+	bx, by := c.toImage(float64(x2), float64(y2))
+	hw := width * pngScale / 2
+	for y := int(math.Floor(min(ay, by) - hw - 1)); y <= int(math.Ceil(max(ay, by)+hw+1)); y++ {
+		for x := int(math.Floor(min(ax, bx) - hw - 1)); x <= int(math.Ceil(max(ax, bx)+hw+1)); x++ {
+			d := segmentDistance(float64(x)+0.5, float64(y)+0.5, ax, ay, bx, by)
+			c.blend(x, y, col, hw+0.5-d)
+		}
+	}
+}
+
+// dashedLine draws a horizontal or vertical line with dashes of
+// groupDash pixels and gaps of groupGap pixels like the stroke-dasharray
+// of the SVG diagrams.
+func (c *canvas) dashedLine(x1, y1, x2, y2 int, width float64, col color.RGBA) {
+	dx, dy := sign(x2-x1), sign(y2-y1)
+	length := max(abs(x2-x1), abs(y2-y1))
+	for d := 0; d < length; d += groupDash + groupGap {
+		e := min(d+groupDash, length)
+		c.line(x1+d*dx, y1+d*dy, x1+e*dx, y1+e*dy, width, col)
+	}
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// segmentDistance returns the distance of point p to the segment from a
+// to b.
+func segmentDistance(px, py, ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l2 := dx*dx + dy*dy; l2 > 0 {
+		t = max(0, min(1, ((px-ax)*dx+(py-ay)*dy)/l2))
+	}
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy))
+}
+
+// rect draws a filled rectangle with rounded corners and a border of the
+// given width centered on its edges.
+func (c *canvas) rect(x, y, w, h, r float64, fill, stroke color.RGBA, width float64) {
+	ix, iy := c.toImage(x, y)
+	hw, hh := w*pngScale/2, h*pngScale/2
+	cx, cy := ix+hw, iy+hh
+	r = min(r*pngScale, hw, hh)
+	sw := width * pngScale / 2
+	for py := int(math.Floor(iy - sw - 1)); py <= int(math.Ceil(iy+2*hh+sw+1)); py++ {
+		for px := int(math.Floor(ix - sw - 1)); px <= int(math.Ceil(ix+2*hw+sw+1)); px++ {
+			// signed distance to the border (negative inside):
+			qx := math.Abs(float64(px)+0.5-cx) - (hw - r)
+			qy := math.Abs(float64(py)+0.5-cy) - (hh - r)
+			d := math.Hypot(max(qx, 0), max(qy, 0)) + min(max(qx, qy), 0) - r
+			c.blend(px, py, fill, 0.5-d)
+			if sw > 0 {
+				c.blend(px, py, stroke, sw+0.5-math.Abs(d))
+			}
+		}
+	}
+}
+
+// polygon draws a filled polygon and a border of the given width centered
+// on its edges.
+func (c *canvas) polygon(points []svgPoint, fill, stroke color.RGBA, width float64) {
+	ps := make([][2]float64, len(points))
+	minX, minY, maxX, maxY := math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64
+	for i, p := range points {
+		x, y := c.toImage(float64(p.X), float64(p.Y))
+		ps[i] = [2]float64{x, y}
+		minX, minY, maxX, maxY = min(minX, x), min(minY, y), max(maxX, x), max(maxY, y)
+	}
+	sw := width * pngScale / 2
+	for py := int(math.Floor(minY - sw - 1)); py <= int(math.Ceil(maxY+sw+1)); py++ {
+		for px := int(math.Floor(minX - sw - 1)); px <= int(math.Ceil(maxX+sw+1)); px++ {
+			x, y := float64(px)+0.5, float64(py)+0.5
+			// signed distance to the border (negative inside):
+			d := math.MaxFloat64
+			inside := false
+			for i := range ps {
+				a, b := ps[i], ps[(i+1)%len(ps)]
+				d = min(d, segmentDistance(x, y, a[0], a[1], b[0], b[1]))
+				if (a[1] > y) != (b[1] > y) && x < a[0]+(y-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+					inside = !inside
+				}
+			}
+			if inside {
+				d = -d
+			}
+			c.blend(px, py, fill, 0.5-d)
+			if sw > 0 {
+				c.blend(px, py, stroke, sw+0.5-math.Abs(d))
+			}
+		}
+	}
+}
+
+// text draws the text with the bitmap font so that it exactly fills its
+// width like the textLength attribute of SVG does.
+func (c *canvas) text(txt *svgText, size float64, col color.RGBA) {
+	cells := 0
+	for _, r := range txt.Text {
+		if _, ok := glyphFor(r); ok {
+			cells += runeUnits(r, 1, 2)
+		}
+	}
+	if cells == 0 || txt.Width <= 0 {
+		return
+	}
+	x, baseY := c.toImage(float64(txt.X), float64(txt.Y))
+	cellWidth := float64(txt.Width) * pngScale / float64(cells)
+	pixelHeight := size * capHeightEms * pngScale / glyphCapRows
+	top := baseY - glyphCapRows*pixelHeight
+
+	coverage := make(map[image.Point]float64, 64)
+	for _, r := range txt.Text {
+		g, ok := glyphFor(r)
+		if !ok {
+			continue
+		}
+		width := cellWidth * float64(runeUnits(r, 1, 2))
+		pixelWidth := width / glyphCellWidth
+		for row, bits := range g {
+			for col := 0; col < glyphCols; col++ {
+				if bits&(1<<(glyphCols-1-col)) != 0 {
+					px := x + float64(col)*pixelWidth
+					py := top + float64(row)*pixelHeight
+					addBox(coverage, px, py, px+pixelWidth, py+pixelHeight)
+				}
+			}
+		}
+		x += width
+	}
+	for p, cov := range coverage {
+		c.blend(p.X, p.Y, col, cov)
+	}
+}
+
+// addBox adds the area of the box that covers each pixel to coverage.
+func addBox(coverage map[image.Point]float64, x0, y0, x1, y1 float64) {
+	for py := int(math.Floor(y0)); float64(py) < y1; py++ {
+		oy := min(y1, float64(py+1)) - max(y0, float64(py))
+		for px := int(math.Floor(x0)); float64(px) < x1; px++ {
+			ox := min(x1, float64(px+1)) - max(x0, float64(px))
+			p := image.Point{X: px, Y: py}
+			coverage[p] = min(1, coverage[p]+ox*oy)
+		}
+	}
+}
@@ -30,7 +30,7 @@ func (prt *StartPort) minRestOfRowWidth(num int) int {
 }
 
 func (prt *StartPort) calcHorizontalValues(x0 int) {
-	prt.drawData = portHorizontalValues(x0, prt.name)
+	prt.drawData = portHorizontalValues(prt.metrics, x0, prt.name)
 	prt.output.calcHorizontalValues(prt.drawData.x0 + prt.drawData.width)
 }
 
@@ -58,7 +58,7 @@ func (prt *StartPort) calcVerticalValues(y0, minLine int, mode FlowMode) (maxLin
 
 func (prt *StartPort) toSVG(smf *svgMDFlow, line int, mode FlowMode) {
 	if prt.drawData.drawLine(line) {
-		portToSVG(smf, line, mode, prt.drawData, prt.name)
+		portToSVG(smf, line, mode, prt.drawData, prt.name, false)
 		prt.drawData.drawnLines[line] = true
 	}
 	prt.output.toSVG(smf, line, mode)
@@ -95,7 +95,7 @@ func (prt *EndPort) minRestOfRowWidth(num int) int {
 }
 
 func (prt *EndPort) calcHorizontalValues(x0 int) {
-	prt.drawData = portHorizontalValues(x0, prt.name)
+	prt.drawData = portHorizontalValues(prt.metrics, x0, prt.name)
 }
 
 func (prt *EndPort) extendArrows() {
@@ -115,7 +115,7 @@ func (prt *EndPort) calcVerticalValues(y0, minLine int, mode FlowMode) (maxLines
 
 func (prt *EndPort) toSVG(smf *svgMDFlow, line int, mode FlowMode) {
 	if prt.drawData.drawLine(line) {
-		portToSVG(smf, line, mode, prt.drawData, prt.name)
+		portToSVG(smf, line, mode, prt.drawData, prt.name, prt.input.isError)
 		prt.drawData.drawnLines[line] = true
 	}
 }
@@ -123,8 +123,8 @@ func (prt *EndPort) toSVG(smf *svgMDFlow, line int, mode FlowMode) {
 // --------------------------------------------------------------------------
 // Helpers:
 // --------------------------------------------------------------------------
-func portHorizontalValues(x0 int, name string) *drawData {
-	return newDrawData(x0, len(name)*CharWidth)
+func portHorizontalValues(tm TextMetrics, x0 int, name string) *drawData {
+	return newDrawData(x0, textWidth(tm, name, false))
 }
 
 func portVerticalValues(d *drawData, ymax, maxLines int) {
@@ -134,7 +134,7 @@ func portVerticalValues(d *drawData, ymax, maxLines int) {
 	d.lines = 1
 }
 
-func portToSVG(smf *svgMDFlow, line int, mode FlowMode, pd *drawData, name string) {
+func portToSVG(smf *svgMDFlow, line int, mode FlowMode, pd *drawData, name string, isError bool) {
 	var svg *svgFlow
 
 	idx := line - pd.minLine
@@ -154,6 +154,7 @@ func portToSVG(smf *svgMDFlow, line int, mode FlowMode, pd *drawData, name strin
 			Y:     pd.y0 + pd.height - arrTextOffset,
 			Width: pd.width,
 			Text:  name,
+			Error: isError,
 		})
 	}
 
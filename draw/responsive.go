@@ -0,0 +1,140 @@
+package draw
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// DefaultWidthStep is the step between the widths of a WidthRange if its
+// Step is 0.
+const DefaultWidthStep = 50
+
+// WidthRange is a range of maximum widths for laying out a flow.
+// The widths from Min to Max are tried in steps of Step.
+// Max is always tried.
+type WidthRange struct {
+	Min, Max, Step int
+}
+
+func (wr WidthRange) widths() []int {
+	step := wr.Step
+	if step <= 0 {
+		step = DefaultWidthStep
+	}
+	minWidth := max(wr.Min, step)
+	widths := make([]int, 0, (wr.Max-minWidth)/step+2)
+	for w := minWidth; w < wr.Max; w += step {
+		widths = append(widths, w)
+	}
+	return append(widths, wr.Max)
+}
+
+// Layouts lays out the flow for all widths of the range.
+// Layouts with the same number of breaks and the same size as the next
+// narrower layout are left out. The layouts are sorted by width.
+// The Width of opts is ignored.
+func (flow *Flow) Layouts(opts LayoutOptions, wr WidthRange) ([]*Layout, error) {
+	layouts := make([]*Layout, 0, 8)
+	for _, w := range wr.widths() {
+		opts.Width = w
+		l, err := flow.Layout(opts)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(layouts); n > 0 && layouts[n-1].sameAs(l) {
+			continue
+		}
+		layouts = append(layouts, l)
+	}
+	return layouts, nil
+}
+
+// BestLayout returns the narrowest layout of the range with the fewest
+// breaks.
+// The Width of opts is ignored.
+func (flow *Flow) BestLayout(opts LayoutOptions, wr WidthRange) (*Layout, error) {
+	layouts, err := flow.Layouts(opts, wr)
+	if err != nil {
+		return nil, err
+	}
+	best := layouts[0]
+	for _, l := range layouts[1:] {
+		if l.breaks < best.breaks {
+			best = l
+		}
+	}
+	return best, nil
+}
+
+func (l *Layout) sameAs(o *Layout) bool {
+	return l.breaks == o.breaks && l.width == o.width && l.height == o.height
+}
+
+// RenderResponsive renders the layouts of the same flow (sorted by width,
+// e.g. from Flow.Layouts) into a single MarkDown file.
+// The diagrams are combined in a <picture> element that chooses the
+// diagram that fits the width of the screen.
+// The widest diagram gets the normal name and the others get their
+// maximum width as suffix.
+// Layouts with many diagrams (FlowModeMDLinks) can't be combined.
+func RenderResponsive(layouts []*Layout, opts RenderOptions) (svgContents map[string][]byte, mdContent []byte, err error) {
+	if len(layouts) == 0 {
+		return nil, nil, errors.New("no layouts to render")
+	}
+	for _, l := range layouts {
+		if l.mode == FlowModeMDLinks && l.direction != LayoutTopDown {
+			return nil, nil, fmt.Errorf("unable to render flow %q responsive: it has got many diagrams", l.name)
+		}
+	}
+	widest := layouts[len(layouts)-1]
+	svgContents, md, err := widest.render(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	naming := opts.Naming
+	if naming == nil {
+		naming = DefaultNaming
+	}
+
+	cell := md.FlowLines[0][0]
+	sources := make([]svgSource, 0, 2*len(layouts))
+	for i, l := range layouts[:len(layouts)-1] {
+		vopts := opts
+		vopts.Naming = widthNaming{Naming: naming, suffix: "-" + strconv.Itoa(l.maxWidth)}
+		vsvgs, vmd, err := l.render(vopts)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, content := range vsvgs {
+			svgContents[name] = content
+		}
+		vcell := vmd.FlowLines[0][0]
+		media := "(max-width: " + strconv.Itoa(layouts[i+1].width-1) + "px)"
+		if vcell.DarkSVG != "" {
+			sources = append(sources, svgSource{Media: "(prefers-color-scheme: dark) and " + media, SVG: vcell.DarkSVG})
+		}
+		sources = append(sources, svgSource{Media: media, SVG: vcell.SVG})
+	}
+	if cell.DarkSVG != "" {
+		sources = append(sources, svgSource{Media: "(prefers-color-scheme: dark)", SVG: cell.DarkSVG})
+	}
+	cell.Sources = sources
+
+	mdContent, err = mdFlowToBytes(md)
+	if err != nil {
+		return nil, nil,
+			fmt.Errorf("unable to create MarkDown content for %q flow: %w", widest.name, err)
+	}
+	return svgContents, mdContent, nil
+}
+
+// widthNaming adds a suffix to the name of the diagram of a flow.
+type widthNaming struct {
+	Naming
+	suffix string
+}
+
+func (n widthNaming) Diagram(flow string) string {
+	return n.Naming.Diagram(flow + n.suffix)
+}
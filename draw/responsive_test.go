@@ -0,0 +1,72 @@
+package draw_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestLayouts(t *testing.T) {
+	flow := buildBigTestFlowData()
+	layouts, err := flow.Layouts(flow.LayoutOptions(), draw.WidthRange{Min: 400, Max: 1500, Step: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(layouts) < 2 {
+		t.Fatalf("expected many different layouts, got: %d", len(layouts))
+	}
+	for i := 1; i < len(layouts); i++ {
+		if layouts[i].Width() < layouts[i-1].Width() {
+			t.Errorf("expected layout %d to be at least %d wide, got: %d", i, layouts[i-1].Width(), layouts[i].Width())
+		}
+	}
+
+	best, err := flow.BestLayout(flow.LayoutOptions(), draw.WidthRange{Min: 400, Max: 1500, Step: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, l := range layouts {
+		if l.Breaks() < best.Breaks() {
+			t.Errorf("expected best layout with at most %d breaks, got: %d", l.Breaks(), best.Breaks())
+		}
+		if l.Breaks() == best.Breaks() && l.Width() < best.Width() {
+			t.Errorf("expected layout %d to be the best one since it is narrower", i)
+		}
+	}
+}
+
+func TestRenderResponsive(t *testing.T) {
+	flow := buildBigTestFlowData()
+	layouts, err := flow.Layouts(flow.LayoutOptions(), draw.WidthRange{Min: 400, Max: 1500, Step: 100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svgs, md, err := draw.RenderResponsive(layouts, draw.RenderOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(svgs) != len(layouts) {
+		t.Errorf("expected %d diagrams, got: %d", len(layouts), len(svgs))
+	}
+	if _, ok := svgs["flowdev/flow-bigTestFlow.svg"]; !ok {
+		t.Errorf("expected widest diagram with the normal name, got: %v", keys(svgs))
+	}
+	if got := strings.Count(string(md), "<source "); got != len(layouts)-1 {
+		t.Errorf("expected %d sources, got %d in:\n%s", len(layouts)-1, got, md)
+	}
+	if !strings.Contains(string(md), `<img alt="bigTestFlow" src="flowdev/flow-bigTestFlow.svg"></picture>`) {
+		t.Errorf("expected widest diagram as default, got:\n%s", md)
+	}
+
+	opts := flow.LayoutOptions()
+	opts.Mode = draw.FlowModeMDLinks
+	split, err := flow.Layout(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _, err = draw.RenderResponsive([]*draw.Layout{split}, draw.RenderOptions{})
+	if err == nil || !strings.Contains(err.Error(), "many diagrams") {
+		t.Errorf("expected error for many diagrams, got: %v", err)
+	}
+}
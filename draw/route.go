@@ -0,0 +1,275 @@
+package draw
+
+import (
+	"strconv"
+)
+
+// UseRouting lets loops back to a component of the same flow and breaks of
+// long rows be drawn as real connections with bends.
+// The connections run in lanes below and left of the diagram and enter
+// the component from below or from the left.
+// If a connection would overlap a component or a text, the loop or break
+// marker is drawn instead.
+// Routing is only done for FlowModeNoLinks and LayoutLeftToRight.
+func (flow *Flow) UseRouting() *Flow {
+	flow.routing = true
+	return flow
+}
+
+// routeBox is a rectangle that connections must not cross.
+type routeBox struct {
+	x0, y0, x1, y1 int
+}
+
+type router struct {
+	svg         *svgFlow
+	height      int // height of the diagram without lanes
+	bottomLanes int
+	leftLanes   int
+}
+
+// routeEdges replaces loop and break markers in the SVG flow with routed
+// connections where possible.
+func (flow *Flow) routeEdges(svg *svgFlow) {
+	loops, breaks, comps := flow.routables()
+	r := &router{svg: svg, height: svg.TotalHeight}
+	for _, loop := range loops {
+		if comp := comps[loop.name]; comp != nil {
+			r.routeLoop(loop, comp)
+		}
+	}
+	for _, brk := range breaks {
+		r.routeBreak(brk)
+	}
+	svg.TotalHeight += r.bottomLanes * LineHeight
+	if r.leftLanes > 0 {
+		margin := (r.leftLanes + 1) * RowGap
+		svg.X0 -= margin
+		svg.TotalWidth += margin
+	}
+}
+
+// routables returns all loops, all break starts and all components by ID.
+func (flow *Flow) routables() ([]*Loop, []*BreakStart, map[string]*Comp) {
+	loops := make([]*Loop, 0, 8)
+	breaks := make([]*BreakStart, 0, 8)
+	comps := make(map[string]*Comp, 64)
+	seen := make(map[anyComp]bool, 64)
+	var visit func(comp anyComp)
+	visit = func(comp anyComp) {
+		if seen[comp] {
+			return
+		}
+		seen[comp] = true
+		switch c := comp.(type) {
+		case *Loop:
+			loops = append(loops, c)
+		case *BreakStart:
+			breaks = append(breaks, c)
+		case *Comp:
+			if _, ok := comps[c.ID()]; !ok {
+				comps[c.ID()] = c
+			}
+		}
+		for _, out := range outputsOf(comp) {
+			visit(out.dstComp)
+		}
+	}
+	for _, cl := range flow.clusters {
+		for _, start := range cl.starts {
+			visit(start)
+		}
+	}
+	return loops, breaks, comps
+}
+
+func (r *router) routeLoop(loop *Loop, comp *Comp) {
+	ld := loop.drawData
+	cd := comp.drawData
+	sx, sy := ld.x0, arrowY(loop.input.drawData)
+	marker := r.findText(ld.x0, ld.ymax()-arrTextOffset)
+	obstacles := r.obstacles(marker)
+	laneY := r.height + (r.bottomLanes+1)*LineHeight - TextOffset
+
+	vx, ok := firstFreeX(sx+WordGap, ld.xmax()-WordGap, func(x int) bool {
+		return segmentFree(obstacles, sx, sy, x, sy) && segmentFree(obstacles, x, sy, x, laneY)
+	})
+	if !ok {
+		return
+	}
+	points := []svgPoint{{sx, sy}, {vx, sy}, {vx, laneY}}
+	path := &svgPath{Tip: true, Error: loop.input.isError, Change: loop.input.change}
+	labelX := 0
+
+	// enter the component from below:
+	if cx, ok := firstFreeX(cd.x0+cd.width/2, cd.xmax()-WordGap, func(x int) bool {
+		return segmentFree(obstacles, x, laneY, x, cd.ymax())
+	}); ok {
+		points = append(points, svgPoint{cx, laneY}, svgPoint{cx, cd.ymax()})
+		path.XTip1, path.YTip1 = cx-arrTipHeight, cd.ymax()+arrTipHeight
+		path.XTip2, path.YTip2 = cx+arrTipHeight, cd.ymax()+arrTipHeight
+		labelX = cx + WordGap
+	} else { // enter the component from the left:
+		lx := -(r.leftLanes + 1) * RowGap
+		ty, ok := r.freeCompLine(comp, obstacles, lx)
+		if !ok {
+			return
+		}
+		points = append(points, svgPoint{lx, laneY}, svgPoint{lx, ty}, svgPoint{cd.x0, ty})
+		path.XTip1, path.YTip1 = cd.x0-arrTipHeight, ty-arrTipHeight
+		path.XTip2, path.YTip2 = cd.x0-arrTipHeight, ty+arrTipHeight
+		labelX = lx + WordGap
+		r.leftLanes++
+	}
+	r.bottomLanes++
+
+	last := points[len(points)-1]
+	path.Points, path.X2, path.Y2 = points, last.X, last.Y
+	r.svg.Paths = append(r.svg.Paths, path)
+	r.removeText(marker)
+	r.removeTip(sx, sy)
+	if loop.port != "" {
+		r.svg.Texts = append(r.svg.Texts, &svgText{
+			X:     labelX,
+			Y:     laneY - arrSmallTextOffset,
+			Width: loop.textWidth(loop.port, true),
+			Text:  loop.port,
+			Small: true,
+		})
+	}
+}
+
+func (r *router) routeBreak(brk *BreakStart) {
+	end := brk.end
+	if end == nil || end.output == nil {
+		return
+	}
+	bd, ed := brk.drawData, end.drawData
+	sx, sy := bd.x0, arrowY(brk.input.drawData)
+	tx, ty := ed.xmax(), arrowY(end.output.drawData)
+	num := BreakText + strconv.Itoa(brk.number)
+	startMarker := r.findText(bd.x0, bd.ymax()-arrTextOffset)
+	endMarker := r.findText(ed.x0, ed.ymax()-arrTextOffset)
+	if startMarker == nil || startMarker.Text != num || endMarker == nil || endMarker.Text != num {
+		return
+	}
+	obstacles := r.obstacles(startMarker, endMarker)
+	laneY := r.height + (r.bottomLanes+1)*LineHeight - TextOffset
+	lx := -(r.leftLanes + 1) * RowGap
+
+	vx, ok := firstFreeX(sx+WordGap, bd.xmax()-WordGap, func(x int) bool {
+		return segmentFree(obstacles, sx, sy, x, sy) && segmentFree(obstacles, x, sy, x, laneY)
+	})
+	if !ok || !segmentFree(obstacles, lx, ty, tx, ty) {
+		return
+	}
+	points := []svgPoint{{sx, sy}, {vx, sy}, {vx, laneY}, {lx, laneY}, {lx, ty}, {tx, ty}}
+	r.svg.Paths = append(r.svg.Paths, &svgPath{Points: points, X2: tx, Y2: ty, Error: brk.input.isError, Change: brk.input.change})
+	r.removeText(startMarker)
+	r.removeText(endMarker)
+	r.removeTip(sx, sy)
+	r.bottomLanes++
+	r.leftLanes++
+}
+
+// freeCompLine returns the y value of a line of the component that can be
+// reached from x without crossing anything.
+func (r *router) freeCompLine(comp *Comp, obstacles []routeBox, x int) (int, bool) {
+	cd := comp.drawData
+	lines := cd.height / LineHeight
+LINES:
+	for l := 0; l < lines; l++ {
+		y := cd.y0 + l*LineHeight + LineHeight/2
+		for _, in := range comp.inputs {
+			ind := in.drawData
+			if ind.ymax()-LineHeight <= y && y <= ind.ymax() {
+				continue LINES
+			}
+		}
+		if segmentFree(obstacles, x, y, cd.x0, y) {
+			return y, true
+		}
+	}
+	return 0, false
+}
+
+// obstacles returns the boxes of all rectangles and texts except the
+// given texts.
+func (r *router) obstacles(except ...*svgText) []routeBox {
+	boxes := make([]routeBox, 0, len(r.svg.Rects)+len(r.svg.Texts))
+	for _, rect := range r.svg.Rects {
+		boxes = append(boxes, routeBox{x0: rect.X, y0: rect.Y, x1: rect.X + rect.Width, y1: rect.Y + rect.Height})
+	}
+TEXTS:
+	for _, txt := range r.svg.Texts {
+		for _, ex := range except {
+			if txt == ex {
+				continue TEXTS
+			}
+		}
+		boxes = append(boxes, routeBox{
+			x0: txt.X, y0: txt.Y - LineHeight + TextOffset,
+			x1: txt.X + txt.Width, y1: txt.Y + arrSmallTextOffset,
+		})
+	}
+	return boxes
+}
+
+func (r *router) findText(x, y int) *svgText {
+	for _, txt := range r.svg.Texts {
+		if txt.X == x && txt.Y == y {
+			return txt
+		}
+	}
+	return nil
+}
+
+func (r *router) removeText(txt *svgText) {
+	for i, t := range r.svg.Texts {
+		if t == txt {
+			r.svg.Texts = append(r.svg.Texts[:i], r.svg.Texts[i+1:]...)
+			return
+		}
+	}
+}
+
+// removeTip removes the tip of the arrow ending at (x, y) because the
+// connection continues there.
+func (r *router) removeTip(x, y int) {
+	for _, arr := range r.svg.Arrows {
+		if arr.X2 == x && arr.Y2 == y {
+			arr.XTip1, arr.YTip1 = x, y
+			arr.XTip2, arr.YTip2 = x, y
+			return
+		}
+	}
+}
+
+// arrowY returns the y value of the line of an arrow.
+func arrowY(ad *drawData) int {
+	return ad.ymax() - LineHeight + arrTipHeight
+}
+
+// firstFreeX returns the first x value from x0 to x1 (in steps of
+// CharWidth) that is free.
+func firstFreeX(x0, x1 int, free func(x int) bool) (int, bool) {
+	for x := x0; x <= max(x0, x1); x += CharWidth {
+		if free(x) {
+			return x, true
+		}
+	}
+	return 0, false
+}
+
+// segmentFree reports whether the horizontal or vertical segment doesn't
+// cross any of the boxes. Touching a box is allowed.
+func segmentFree(boxes []routeBox, x0, y0, x1, y1 int) bool {
+	x0, x1 = min(x0, x1), max(x0, x1)
+	y0, y1 = min(y0, y1), max(y0, y1)
+	for _, b := range boxes {
+		if x0 < b.x1 && b.x0 < x1 && y0 < b.y1 && b.y0 < y1 {
+			return false
+		}
+	}
+	return true
+}
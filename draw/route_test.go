@@ -0,0 +1,78 @@
+package draw_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestRouting(t *testing.T) {
+	specs := []struct {
+		name              string
+		givenFlow         func() *draw.Flow
+		givenSVG          string
+		expectedPaths     int
+		expectedContent   []string
+		unexpectedContent []string
+	}{
+		{
+			name: "loop",
+			givenFlow: func() *draw.Flow {
+				return buildSmallTestFlow().UseRouting()
+			},
+			givenSVG:      "flowdev/flow-smallTestFlow.svg",
+			expectedPaths: 1,
+			expectedContent: []string{
+				`points="318,96 324,96 324,130 186,130 186,112"`,
+				`lengthAdjust="spacingAndGlyphs">in</text>`,
+			},
+			unexpectedContent: []string{draw.LoopText},
+		}, {
+			name: "breaks",
+			givenFlow: func() *draw.Flow {
+				flow := buildBigTestFlowData()
+				flow.ChangeConfig("bigTestFlow", draw.FlowModeNoLinks, 750, nil)
+				return flow.UseRouting()
+			},
+			givenSVG:          "flowdev/flow-bigTestFlow.svg",
+			expectedPaths:     4,
+			expectedContent:   []string{`points="701,8 707,8 707,730 -8,730 -8,416 20,416"`, `viewBox="-32 0 `},
+			unexpectedContent: []string{draw.BreakText},
+		}, {
+			name: "no-routing",
+			givenFlow: func() *draw.Flow {
+				return buildSmallTestFlow()
+			},
+			givenSVG:        "flowdev/flow-smallTestFlow.svg",
+			expectedPaths:   0,
+			expectedContent: []string{draw.LoopText},
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			svgs, _, err := spec.givenFlow().Draw()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			svg := string(svgs[spec.givenSVG])
+			if svg == "" {
+				t.Fatalf("SVG %q not found", spec.givenSVG)
+			}
+			if actualPaths := strings.Count(svg, "<polyline"); actualPaths != spec.expectedPaths {
+				t.Errorf("expected %d paths, got: %d", spec.expectedPaths, actualPaths)
+			}
+			for _, expected := range spec.expectedContent {
+				if !strings.Contains(svg, expected) {
+					t.Errorf("expected SVG to contain %q, got:\n%s", expected, svg)
+				}
+			}
+			for _, unexpected := range spec.unexpectedContent {
+				if strings.Contains(svg, unexpected) {
+					t.Errorf("expected SVG not to contain %q, got:\n%s", unexpected, svg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package draw
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CompKind is the kind of a component. It decides about the shape of the
+// component, so diagrams show at a glance which components do I/O.
+type CompKind int
+
+const (
+	KindFunc     CompKind = iota // plain Go function: rounded rectangle
+	KindFlow                     // flow: rectangle with double vertical borders
+	KindDatabase                 // database: cylinder
+	KindService                  // external service: rectangle with round ends
+	KindQueue                    // queue: lying cylinder
+	KindDecision                 // decision: diamond flattened to fit the texts
+)
+
+var compKindNames = []string{"func", "flow", "database", "service", "queue", "decision"}
+
+func (k CompKind) String() string {
+	if k < 0 || int(k) >= len(compKindNames) {
+		return fmt.Sprintf("CompKind(%d)", int(k))
+	}
+	return compKindNames[k]
+}
+
+// ParseCompKind returns the component kind with the given name.
+// The empty name is KindFunc.
+func ParseCompKind(name string) (CompKind, error) {
+	if name == "" {
+		return KindFunc, nil
+	}
+	for i, n := range compKindNames {
+		if n == name {
+			return CompKind(i), nil
+		}
+	}
+	return KindFunc, fmt.Errorf("unknown component kind %q (expected one of: %v)", name, compKindNames)
+}
+
+const (
+	flowBarGap     = 4  // distance of the inner borders of a flow
+	dbCurve        = 4  // vertical radius of the ellipses of a database
+	queueCurve     = 4  // horizontal radius of the ellipses of a queue
+	decisionIndent = 12 // horizontal size of the tips of a decision
+	shapeSegments  = 8  // number of lines of a half ellipse
+)
+
+// SetKind changes the kind and so the shape of the component.
+func (comp *Comp) SetKind(kind CompKind) *Comp {
+	comp.kind = kind
+	return comp
+}
+
+// SetClass sets the CSS class of the shape of the component.
+// The CSS can be added to the diagrams with Theme.CSS.
+// The shapes of all kinds except KindFunc have got the class
+// "flowdev-<kind>" too.
+func (comp *Comp) SetClass(class string) *Comp {
+	comp.class = class
+	return comp
+}
+
+// SetStyle sets the inline CSS style of the shape of the component
+// (e.g. "fill: orange"). It overrides the colors of the theme.
+// Classes and styles are only used in SVG diagrams.
+func (comp *Comp) SetStyle(style string) *Comp {
+	comp.style = style
+	return comp
+}
+
+// kindPadding returns the additional horizontal space the shape needs left
+// and right of the texts.
+func kindPadding(kind CompKind) (left, right int) {
+	switch kind {
+	case KindFlow:
+		return flowBarGap, flowBarGap
+	case KindService:
+		return LineHeight / 4, LineHeight / 4
+	case KindQueue:
+		return 0, 2 * queueCurve
+	case KindDecision:
+		return decisionIndent, decisionIndent
+	default:
+		return 0, 0
+	}
+}
+
+// shapeToSVG adds the outer shape of the component.
+func (comp *Comp) shapeToSVG(svg *svgFlow, d *drawData) {
+	rectToSVG(svg, d, false, false, false)
+	rect := svg.Rects[len(svg.Rects)-1]
+	rect.Kind = comp.kind
+	rect.Class = comp.class
+	rect.Style = comp.style
+	rect.Change = comp.change
+}
+
+// Shaped tells if the rectangle is the outer shape of a component with
+// its own kind, class, style or change.
+func (r *svgRect) Shaped() bool {
+	return r.Kind != KindFunc || r.Class != "" || r.Style != "" || r.Change != Unchanged
+}
+
+// Classes returns the CSS classes of the shape.
+// Added and removed components have got the class "flowdev-added" or
+// "flowdev-removed" too.
+func (r *svgRect) Classes() string {
+	classes := make([]string, 0, 3)
+	if r.Kind != KindFunc {
+		classes = append(classes, "flowdev-"+r.Kind.String())
+	}
+	if r.Change != Unchanged {
+		classes = append(classes, "flowdev-"+r.Change.String())
+	}
+	if r.Class != "" {
+		classes = append(classes, r.Class)
+	}
+	return strings.Join(classes, " ")
+}
+
+// Radius returns the corner radius of a shape without outline.
+// 0 means the radius of the theme.
+func (r *svgRect) Radius() int {
+	if r.Kind == KindService {
+		return LineHeight
+	}
+	return 0
+}
+
+// Outline returns the closed outline of the shape.
+// It is nil for (rounded) rectangles.
+func (r *svgRect) Outline() []svgPoint {
+	x0, y0, x1, y1 := r.X, r.Y, r.X+r.Width, r.Y+r.Height
+	switch r.Kind {
+	case KindFlow:
+		return []svgPoint{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+	case KindDatabase:
+		ry := min(dbCurve, r.Height/4)
+		outline := ellipseArc(r.X+r.Width/2, y0+ry, r.Width/2, ry, math.Pi, 2*math.Pi)
+		return append(outline, ellipseArc(r.X+r.Width/2, y1-ry, r.Width/2, ry, 0, math.Pi)...)
+	case KindQueue:
+		rx := min(queueCurve, r.Width/4)
+		outline := ellipseArc(x1-rx, r.Y+r.Height/2, rx, r.Height/2, -math.Pi/2, math.Pi/2)
+		return append(outline, ellipseArc(x0+rx, r.Y+r.Height/2, rx, r.Height/2, math.Pi/2, 3*math.Pi/2)...)
+	case KindDecision:
+		i := min(decisionIndent, r.Height/2)
+		ym := r.Y + r.Height/2
+		return []svgPoint{{x0, ym}, {x0 + i, y0}, {x1 - i, y0}, {x1, ym}, {x1 - i, y1}, {x0 + i, y1}}
+	default:
+		return nil
+	}
+}
+
+// Marks returns the additional lines of the shape.
+func (r *svgRect) Marks() [][]svgPoint {
+	x0, y0, x1, y1 := r.X, r.Y, r.X+r.Width, r.Y+r.Height
+	switch r.Kind {
+	case KindFlow:
+		return [][]svgPoint{
+			{{x0 + flowBarGap, y0}, {x0 + flowBarGap, y1}},
+			{{x1 - flowBarGap, y0}, {x1 - flowBarGap, y1}},
+		}
+	case KindDatabase:
+		ry := min(dbCurve, r.Height/4)
+		return [][]svgPoint{ellipseArc(r.X+r.Width/2, y0+ry, r.Width/2, ry, math.Pi, 0)}
+	case KindQueue:
+		rx := min(queueCurve, r.Width/4)
+		return [][]svgPoint{ellipseArc(x1-rx, r.Y+r.Height/2, rx, r.Height/2, math.Pi/2, 3*math.Pi/2)}
+	default:
+		return nil
+	}
+}
+
+// ellipseArc returns the points of the arc of an ellipse from angle a0 to
+// a1. Angles grow clockwise on screen.
+func ellipseArc(cx, cy, rx, ry int, a0, a1 float64) []svgPoint {
+	points := make([]svgPoint, 0, shapeSegments+1)
+	for i := 0; i <= shapeSegments; i++ {
+		a := a0 + (a1-a0)*float64(i)/shapeSegments
+		points = append(points, svgPoint{
+			X: cx + int(math.Round(float64(rx)*math.Cos(a))),
+			Y: cy + int(math.Round(float64(ry)*math.Sin(a))),
+		})
+	}
+	return points
+}
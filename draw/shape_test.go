@@ -0,0 +1,36 @@
+package draw_test
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestDrawShapesPNG(t *testing.T) {
+	pi := drawPNGImage(t, archiveFlow(t, "shape.txt", "shapes.json"), 0, 0, 535, 48)
+
+	background := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	comp := color.RGBA{R: 96, G: 192, B: 255, A: 255}
+	text := color.RGBA{A: 255}
+	pi.checkColor(t, "database mark", 188, 9, text)
+	pi.checkColor(t, "rounded corner of service", 241, 2, background)
+	pi.checkColor(t, "queue mark", 385, 24, text)
+	pi.checkColor(t, "corner outside of decision", 408, 3, background)
+	pi.checkColor(t, "inside of decision", 490, 24, comp)
+}
+
+func TestDrawShapesPDF(t *testing.T) {
+	pdf := drawFile(t, archiveFlow(t, "shape.txt", "shapes.json"), draw.FormatPDF)
+
+	if n := bytes.Count(pdf, []byte(" h B\n")); n != 4 { // flow, database, queue and decision
+		t.Errorf("expected 4 outlines, got %d in:\n%s", n, pdf)
+	}
+	if !bytes.Contains(pdf, []byte("\n406 24 m 418 1 l 486 1 l 498 24 l 486 47 l 418 47 l h B\n")) {
+		t.Errorf("expected the outline of the decision, got:\n%s", pdf)
+	}
+	if !bytes.Contains(pdf, []byte("\n263 1 m\n")) { // service is rounded with half of its height
+		t.Errorf("expected the rounded rectangle of the service, got:\n%s", pdf)
+	}
+}
@@ -0,0 +1,243 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Theme contains all colors, fonts and line styles used for drawing a flow.
+// Colors are given as "rgb(r,g,b)", "rgba(r,g,b,a)", "#rrggbb", "#rgb"
+// (with optional alpha) or as color name. PNG and PDF support only
+// "rgb(r,g,b)", "#rrggbb" and "#rgb".
+// If FontFamily is empty, the default font of the SVG viewer is used.
+// CSS is added to the style of all SVG diagrams, e.g. for the classes of
+// the components (see Comp.SetClass).
+type Theme struct {
+	Background string `json:"background"`
+	Text       string `json:"text"`
+	Link       string `json:"link"`
+	GoLink     string `json:"goLink"`
+	Comp       string `json:"comp"`
+	Plugin     string `json:"plugin"`
+	PluginType string `json:"pluginType"`
+	Error      string `json:"error"`
+
+	FontFamily    string `json:"fontFamily"`
+	FontSize      int    `json:"fontSize"`
+	SmallFontSize int    `json:"smallFontSize"`
+
+	StrokeWidth     int `json:"strokeWidth"`
+	ThinStrokeWidth int `json:"thinStrokeWidth"`
+	CornerRadius    int `json:"cornerRadius"`
+
+	CSS string `json:"css"`
+
+	// Dark contains the colors used if the viewer prefers a dark color
+	// scheme. It is only set for themes created with AutoTheme.
+	Dark *Theme `json:"-"`
+}
+
+// LightTheme returns the default theme with dark text on a light background.
+func LightTheme() *Theme {
+	return &Theme{
+		Background: "rgb(255,255,255)",
+		Text:       "rgb(0,0,0)",
+		Link:       "rgb(32,48,128)",
+		GoLink:     "rgb(0,96,0)",
+		Comp:       "rgb(96,192,255)",
+		Plugin:     "rgb(224,224,32)",
+		PluginType: "rgb(32,224,32)",
+		Error:      "rgb(192,0,0)",
+
+		FontSize:      16,
+		SmallFontSize: 14,
+
+		StrokeWidth:     2,
+		ThinStrokeWidth: 1,
+		CornerRadius:    10,
+	}
+}
+
+// DarkTheme returns a theme with light text on a dark background.
+func DarkTheme() *Theme {
+	t := LightTheme()
+	t.Background = "rgb(13,17,23)"
+	t.Text = "rgb(201,209,217)"
+	t.Link = "rgb(96,192,255)"
+	t.GoLink = "rgb(32,224,32)"
+	t.Comp = "rgb(32,48,128)"
+	t.Plugin = "rgb(96,96,0)"
+	t.PluginType = "rgb(0,96,0)"
+	t.Error = "rgb(248,81,73)"
+	return t
+}
+
+// AutoTheme returns a theme that adapts to the color scheme preferred by
+// the viewer. The colors are taken from light and dark, and everything
+// else from light.
+// By default the colors are switched with CSS inside the SVG files.
+// Flow.UsePictures creates separate files instead.
+func AutoTheme(light, dark *Theme) *Theme {
+	t := *themeOrDefault(light)
+	d := *themeOrDefault(dark)
+	d.Dark = nil
+	t.Dark = &d
+	return &t
+}
+
+// lightOnly returns the theme without its dark variant.
+func (t *Theme) lightOnly() *Theme {
+	lt := *t
+	lt.Dark = nil
+	return &lt
+}
+
+// darkOnly returns the dark variant of the theme with all non-color
+// values of the theme itself.
+func (t *Theme) darkOnly() *Theme {
+	dt := *t
+	dt.Dark = nil
+	dt.Background = t.Dark.Background
+	dt.Text = t.Dark.Text
+	dt.Link = t.Dark.Link
+	dt.GoLink = t.Dark.GoLink
+	dt.Comp = t.Dark.Comp
+	dt.Plugin = t.Dark.Plugin
+	dt.PluginType = t.Dark.PluginType
+	dt.Error = t.Dark.Error
+	return &dt
+}
+
+// cssTheme returns a theme that uses CSS variables for all colors and the
+// CSS style defining the variables for the light and dark color scheme.
+func cssTheme(t *Theme) (*Theme, string) {
+	ct := *t
+	ct.Dark = nil
+	names := []string{"background", "text", "link", "go-link", "comp", "plugin", "plugin-type", "error"}
+	colors := []*string{&ct.Background, &ct.Text, &ct.Link, &ct.GoLink, &ct.Comp, &ct.Plugin, &ct.PluginType, &ct.Error}
+	dark := t.darkOnly()
+	darkColors := []string{dark.Background, dark.Text, dark.Link, dark.GoLink, dark.Comp, dark.Plugin, dark.PluginType, dark.Error}
+
+	sb := &strings.Builder{}
+	sb.WriteString("\n        svg {")
+	for i, name := range names {
+		sb.WriteString("\n            --flowdev-" + name + ": " + *colors[i] + ";")
+		*colors[i] = "var(--flowdev-" + name + ")"
+	}
+	sb.WriteString("\n        }")
+	sb.WriteString("\n        @media (prefers-color-scheme: dark) {")
+	sb.WriteString("\n            svg {")
+	for i, name := range names {
+		sb.WriteString("\n                --flowdev-" + name + ": " + darkColors[i] + ";")
+	}
+	sb.WriteString("\n            }")
+	sb.WriteString("\n        }")
+	return &ct, sb.String()
+}
+
+// LoadTheme reads a theme from the JSON file with the given name.
+// See ThemeFromJSON for the format.
+func LoadTheme(name string) (*Theme, error) {
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read theme file: %w", err)
+	}
+	t, err := ThemeFromJSON(bs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read theme file %q: %w", name, err)
+	}
+	return t, nil
+}
+
+// ThemeFromJSON creates a theme from its JSON description.
+// The keys are the JSON names of the Theme fields.
+// All values that are missing are taken from the base theme.
+// The base theme is given with the key "base" and is either "light"
+// (default) or "dark".
+// An AutoTheme is created if the key "dark" contains the description
+// of the dark theme. Its base theme defaults to "dark".
+func ThemeFromJSON(bs []byte) (*Theme, error) {
+	return themeFromJSON(bs, "light")
+}
+
+func themeFromJSON(bs []byte, defaultBase string) (*Theme, error) {
+	base := struct {
+		Base string `json:"base"`
+	}{}
+	if err := json.Unmarshal(bs, &base); err != nil {
+		return nil, fmt.Errorf("unable to decode JSON: %w", err)
+	}
+	if base.Base == "" {
+		base.Base = defaultBase
+	}
+
+	var t *Theme
+	switch base.Base {
+	case "light":
+		t = LightTheme()
+	case "dark":
+		t = DarkTheme()
+	default:
+		return nil, fmt.Errorf("unknown base theme %q (expected 'light' or 'dark')", base.Base)
+	}
+
+	theme := struct {
+		*Theme
+		Base string          `json:"base"`
+		Dark json.RawMessage `json:"dark"`
+	}{Theme: t}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&theme); err != nil {
+		return nil, fmt.Errorf("unable to decode JSON: %w", err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	if len(theme.Dark) > 0 {
+		if defaultBase == "dark" {
+			return nil, fmt.Errorf("a dark theme can't contain another dark theme")
+		}
+		dark, err := themeFromJSON(theme.Dark, "dark")
+		if err != nil {
+			return nil, fmt.Errorf("dark theme: %w", err)
+		}
+		t = AutoTheme(t, dark)
+	}
+	return t, nil
+}
+
+// colorPattern matches the colors a theme can use: '#rgb', '#rgba',
+// '#rrggbb', '#rrggbbaa', 'rgb(r,g,b)', 'rgba(r,g,b,a)' and color names.
+// So a color can't break out of its SVG attribute.
+var colorPattern = regexp.MustCompile(`^(?:#(?:[0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})|` +
+	`rgba?\(\s*\d{1,3}%?\s*(?:,\s*\d{1,3}%?\s*){2}(?:,\s*(?:0|1|0?\.\d+)\s*)?\)|[a-zA-Z]+)$`)
+
+func (t *Theme) validate() error {
+	names := []string{"background", "text", "link", "goLink", "comp", "plugin", "pluginType", "error"}
+	colors := []string{t.Background, t.Text, t.Link, t.GoLink, t.Comp, t.Plugin, t.PluginType, t.Error}
+	for i, col := range colors {
+		if !colorPattern.MatchString(col) {
+			return fmt.Errorf("invalid %s color %q (expected e.g. 'rgb(r,g,b)', '#rrggbb' or a color name)", names[i], col)
+		}
+	}
+	if t.FontSize <= 0 || t.SmallFontSize <= 0 {
+		return fmt.Errorf("font sizes have to be positive, got: %d and %d", t.FontSize, t.SmallFontSize)
+	}
+	if t.StrokeWidth < 0 || t.ThinStrokeWidth < 0 || t.CornerRadius < 0 {
+		return fmt.Errorf("stroke widths and corner radius can't be negative, got: %d, %d and %d",
+			t.StrokeWidth, t.ThinStrokeWidth, t.CornerRadius)
+	}
+	return nil
+}
+
+func themeOrDefault(theme *Theme) *Theme {
+	if theme == nil {
+		return LightTheme()
+	}
+	return theme
+}
@@ -0,0 +1,166 @@
+package draw_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestThemeFromJSON(t *testing.T) {
+	theme, err := draw.ThemeFromJSON([]byte(`{
+		"base": "dark",
+		"comp": "#ff0000",
+		"fontFamily": "Verdana, sans-serif",
+		"fontSize": 18,
+		"strokeWidth": 3,
+		"cornerRadius": 0
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if theme.Background != draw.DarkTheme().Background {
+		t.Errorf("expected background of dark theme, got: %q", theme.Background)
+	}
+
+	flow := buildSmallTestFlow().SetTheme(theme)
+	svgContents, _, err := flow.Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svg := string(svgContents["flowdev/flow-smallTestFlow.svg"])
+	for _, expected := range []string{
+		`fill="#ff0000"`,
+		`font-family="Verdana, sans-serif"`,
+		`font-size="18"`,
+		`font-size="14"`,
+		`stroke-width="3"`,
+		`rx="0"`,
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("expected SVG to contain %q, got:\n%s", expected, svg)
+		}
+	}
+}
+
+func TestThemeFromJSONErrors(t *testing.T) {
+	specs := []struct {
+		name          string
+		givenJSON     string
+		expectedError string
+	}{
+		{
+			name:          "unknown-base",
+			givenJSON:     `{"base": "pink"}`,
+			expectedError: "unknown base theme",
+		}, {
+			name:          "unknown-field",
+			givenJSON:     `{"comps": "#fff"}`,
+			expectedError: "unknown field",
+		}, {
+			name:          "zero-font-size",
+			givenJSON:     `{"smallFontSize": 0}`,
+			expectedError: "font sizes have to be positive",
+		}, {
+			name:          "quote-in-color",
+			givenJSON:     `{"comp": "red\" onload=\"alert(1)"}`,
+			expectedError: "invalid comp color",
+		}, {
+			name:          "markup-in-dark-color",
+			givenJSON:     `{"dark": {"background": "<b>"}}`,
+			expectedError: "invalid background color",
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			_, err := draw.ThemeFromJSON([]byte(spec.givenJSON))
+			if err == nil {
+				t.Fatalf("expected error containing %q, got none", spec.expectedError)
+			}
+			if !strings.Contains(err.Error(), spec.expectedError) {
+				t.Errorf("expected error containing %q, got: %v", spec.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAutoThemeWithCSS(t *testing.T) {
+	flow := buildSmallTestFlow().SetTheme(draw.AutoTheme(draw.LightTheme(), draw.DarkTheme()))
+	svgContents, _, err := flow.Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(svgContents) != 1 {
+		t.Fatalf("expected exactly 1 SVG file, got: %d", len(svgContents))
+	}
+	svg := string(svgContents["flowdev/flow-smallTestFlow.svg"])
+	for _, expected := range []string{
+		"<style>",
+		"--flowdev-comp: " + draw.LightTheme().Comp + ";",
+		"@media (prefers-color-scheme: dark) {",
+		"--flowdev-comp: " + draw.DarkTheme().Comp + ";",
+		`fill="var(--flowdev-comp)"`,
+		`stroke="var(--flowdev-text)"`,
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("expected SVG to contain %q, got:\n%s", expected, svg)
+		}
+	}
+}
+
+func TestThemeEscaped(t *testing.T) {
+	theme := draw.LightTheme()
+	theme.Comp = `red" onload="alert(1)`
+	theme.CSS = `text > tspan { content: "a & b ]]>"; }`
+	svgContents, _, err := buildSmallTestFlow().SetTheme(theme).Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svg := svgContents["flowdev/flow-smallTestFlow.svg"]
+	dec := xml.NewDecoder(bytes.NewReader(svg))
+	for {
+		if _, err = dec.Token(); err != nil {
+			break
+		}
+	}
+	if err != io.EOF {
+		t.Errorf("expected well-formed XML, got: %v\n%s", err, svg)
+	}
+	if strings.Contains(string(svg), ` onload="`) {
+		t.Errorf("expected the color to stay in its attribute, got:\n%s", svg)
+	}
+}
+
+func TestAutoThemeWithPictures(t *testing.T) {
+	theme, err := draw.ThemeFromJSON([]byte(`{"dark": {"comp": "#123456"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flow := buildSmallTestFlow().SetTheme(theme).UsePictures()
+	svgContents, mdContent, err := flow.Draw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	light := string(svgContents["flowdev/flow-smallTestFlow.svg"])
+	dark := string(svgContents["flowdev/flow-smallTestFlow-dark.svg"])
+	if !strings.Contains(light, `fill="`+draw.LightTheme().Comp+`"`) {
+		t.Errorf("expected light SVG with light colors, got:\n%s", light)
+	}
+	if !strings.Contains(dark, `fill="#123456"`) || !strings.Contains(dark, draw.DarkTheme().Background) {
+		t.Errorf("expected dark SVG with dark colors, got:\n%s", dark)
+	}
+	if strings.Contains(light+dark, "<style>") {
+		t.Errorf("expected no CSS in pictures")
+	}
+
+	expectedMD := `<picture><source media="(prefers-color-scheme: dark)" srcset="flowdev/flow-smallTestFlow-dark.svg">` +
+		`<img alt="smallTestFlow" src="flowdev/flow-smallTestFlow.svg"></picture>`
+	if !strings.Contains(string(mdContent), expectedMD) {
+		t.Errorf("expected MarkDown to contain %q, got:\n%s", expectedMD, mdContent)
+	}
+}
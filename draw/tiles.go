@@ -0,0 +1,89 @@
+package draw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+)
+
+// tileHashLen is the number of hex digits of the content hash used in the
+// names of shared tiles.
+const tileHashLen = 16
+
+// UseSharedTiles names the small diagrams of FlowModeMDLinks by the hash of
+// their content instead of their position in the flow.
+// So identical tiles (e.g. fillers and arrow segments) are stored only once
+// and can be shared by many flows.
+// The names are given by the SharedTile method of the naming.
+func (flow *Flow) UseSharedTiles() *Flow {
+	flow.sharedTiles = true
+	return flow
+}
+
+// moveTilesToOrigin moves the contents of all tiles so their view boxes
+// start at (0, 0).
+// So tiles that look the same have the same content independent of their
+// position in the flow.
+func moveTilesToOrigin(sfs map[string]*svgFlow) {
+	for _, sf := range sfs {
+		sf.move(-sf.X0, -sf.Y0)
+	}
+}
+
+// move moves the view box and all shapes of the SVG flow by (dx, dy).
+func (sf *svgFlow) move(dx, dy int) {
+	sf.X0 += dx
+	sf.Y0 += dy
+	for _, g := range sf.Groups {
+		g.X, g.Y = g.X+dx, g.Y+dy
+		g.TextX, g.TextY = g.TextX+dx, g.TextY+dy
+	}
+	for _, a := range sf.Arrows {
+		a.X1, a.Y1 = a.X1+dx, a.Y1+dy
+		a.X2, a.Y2 = a.X2+dx, a.Y2+dy
+		a.XTip1, a.YTip1 = a.XTip1+dx, a.YTip1+dy
+		a.XTip2, a.YTip2 = a.XTip2+dx, a.YTip2+dy
+	}
+	for _, p := range sf.Paths {
+		for i := range p.Points {
+			p.Points[i].X += dx
+			p.Points[i].Y += dy
+		}
+		p.X2, p.Y2 = p.X2+dx, p.Y2+dy
+		p.XTip1, p.YTip1 = p.XTip1+dx, p.YTip1+dy
+		p.XTip2, p.YTip2 = p.XTip2+dx, p.YTip2+dy
+	}
+	for _, r := range sf.Rects {
+		r.X, r.Y = r.X+dx, r.Y+dy
+	}
+	for _, m := range sf.Markers {
+		m.X, m.Y = m.X+dx, m.Y+dy
+	}
+	for _, t := range sf.Texts {
+		t.X, t.Y = t.X+dx, t.Y+dy
+	}
+}
+
+// shareTiles renames all diagram files to the hash of their content and
+// changes the links in the MarkDown accordingly.
+func shareTiles(files map[string][]byte, md *mdFlow, naming Naming) map[string][]byte {
+	shared := make(map[string][]byte, len(files))
+	names := make(map[string]string, len(files))
+	for name, content := range files {
+		sum := sha256.Sum256(content)
+		ext := path.Ext(name)
+		newName := strings.TrimSuffix(naming.SharedTile(hex.EncodeToString(sum[:])[:tileHashLen]), ".svg") + ext
+		shared[newName] = content
+		names[name] = newName
+	}
+	for _, flowLine := range md.FlowLines {
+		for _, cell := range flowLine {
+			cell.SVG = names[cell.SVG]
+			if cell.DarkSVG != "" {
+				cell.DarkSVG = names[cell.DarkSVG]
+			}
+		}
+	}
+	return shared
+}
@@ -0,0 +1,53 @@
+package draw_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestDrawSharedTiles(t *testing.T) {
+	drawSplit := func(shared, pictures bool) (map[string][]byte, string) {
+		flow := buildBigTestFlowData()
+		flow.ChangeConfig("bigTestFlow", draw.FlowModeMDLinks, 1500, draw.AutoTheme(nil, nil))
+		if shared {
+			flow.UseSharedTiles()
+		}
+		if pictures {
+			flow.UsePictures()
+		}
+		files, md, err := flow.Draw()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return files, string(md)
+	}
+
+	tiles, _ := drawSplit(false, false)
+	sharedTiles, md := drawSplit(true, false)
+	if len(sharedTiles) >= len(tiles) {
+		t.Errorf("expected less than %d shared tiles, got: %d", len(tiles), len(sharedTiles))
+	}
+	tileName := regexp.MustCompile(`^flowdev/tile-[0-9a-f]{16}\.svg$`)
+	for name := range sharedTiles {
+		if !tileName.MatchString(name) {
+			t.Errorf("expected tile to be named by its content, got: %q", name)
+		}
+		if !strings.Contains(md, "("+name+")") {
+			t.Errorf("expected a link to tile %q", name)
+		}
+	}
+	againTiles, againMD := drawSplit(true, false)
+	if againMD != md || len(againTiles) != len(sharedTiles) {
+		t.Errorf("expected the same tiles when drawing again")
+	}
+
+	pictureTiles, md := drawSplit(true, true)
+	for name := range pictureTiles {
+		if !strings.Contains(md, `"`+name+`"`) {
+			t.Errorf("expected a link to picture tile %q", name)
+		}
+	}
+}
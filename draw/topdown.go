@@ -0,0 +1,374 @@
+package draw
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// LayoutDirection is the direction in which the components of a flow follow
+// each other.
+type LayoutDirection int
+
+const (
+	// LayoutLeftToRight lays out a flow in rows. Rows that are wider than
+	// the width of the flow are broken and continued below.
+	LayoutLeftToRight LayoutDirection = iota
+	// LayoutTopDown lays out a flow in columns from top to bottom.
+	// The outputs of a component are placed side by side.
+	// The width of the flow is ignored and a single SVG file is created,
+	// even for FlowModeMDLinks. The links of its components, plugins and
+	// data types are listed below the diagram then.
+	LayoutTopDown
+)
+
+const (
+	tdArrowIndent = 2 * WordGap // position of the arrow line in its column
+	tdColumnGap   = 3 * WordGap
+	tdClusterGap  = LineHeight
+)
+
+// topDown collects all shapes of a flow laid out from top to bottom in the
+// order they have been placed.
+type topDown struct {
+	comps  []anyComp
+	arrows []*Arrow
+}
+
+func (flow *Flow) topDownToSVGs() *svgMDFlow {
+	td := &topDown{}
+	width, height := 0, 0
+	for _, cl := range flow.clusters {
+		for _, start := range cl.starts {
+			td.calcY(start, height)
+		}
+		x0 := 0
+		for i, start := range cl.starts {
+			if i > 0 {
+				x0 += tdColumnGap
+			}
+			x0 = td.calcX(start, x0)
+		}
+		width = max(width, x0)
+		height = td.extendArrows() + tdClusterGap
+	}
+	height -= tdClusterGap
+
+	smf := &svgMDFlow{
+		svgs:     make(map[string]*svgFlow, 1),
+		md:       newMDFlow(),
+		naming:   flow.namingOrDefault(),
+		flowName: flow.name,
+	}
+	smf.md.Flow = svgLink{
+		Name: flow.name,
+		SVG:  smf.naming.Diagram(flow.name),
+	}
+	svg := newSVGFlow(0, 0, height, width+1, bigDiagramSize)
+	smf.svgs[""] = svg
+	var md *mdFlow // collects the links that can't be put on tiles
+	if flow.mode == FlowModeMDLinks {
+		md = smf.md
+	}
+	for _, arr := range td.arrows {
+		tdArrowToSVG(svg, md, arr)
+	}
+	for _, comp := range td.comps {
+		tdCompToSVG(svg, md, comp)
+	}
+	return smf
+}
+
+// --------------------------------------------------------------------------
+// Calculate vertical values of shapes (y0 and height)
+// --------------------------------------------------------------------------
+func (td *topDown) calcY(comp anyComp, y0 int) {
+	switch c := comp.(type) {
+	case *StartPort:
+		c.drawData = &drawData{y0: y0, height: LineHeight}
+		td.calcArrowY(c.output, y0+LineHeight)
+	case *BreakEnd:
+		c.drawData = &drawData{y0: y0, height: LineHeight}
+		td.calcArrowY(c.output, y0+LineHeight)
+	case *Comp:
+		if c.drawData != nil && c.drawData.y0 >= y0 {
+			return
+		}
+		if c.drawData == nil {
+			c.drawData = &drawData{height: tdCompLines(c) * LineHeight}
+		}
+		c.drawData.y0 = y0
+		for _, out := range c.outputs {
+			td.calcArrowY(out, c.drawData.ymax())
+		}
+	case *EndPort:
+		c.drawData = &drawData{y0: y0, height: LineHeight}
+	case *Loop:
+		c.drawData = &drawData{y0: y0, height: LineHeight}
+	case *BreakStart:
+		c.drawData = &drawData{y0: y0, height: LineHeight}
+	default:
+		panic(fmt.Sprintf("unable to lay out unknown anyComp: %T", comp))
+	}
+}
+
+func (td *topDown) calcArrowY(arr *Arrow, y0 int) {
+	if arr.drawData != nil && arr.drawData.y0 >= y0 {
+		return
+	}
+	if arr.drawData == nil {
+		lines := len(arr.notes) + len(arr.dataTypes)
+		if arr.srcPort != "" {
+			lines++
+		}
+		if arr.dstPort != "" {
+			lines++
+		}
+		arr.drawData = &drawData{height: max(lines, 1)*LineHeight + LineHeight/2}
+	}
+	arr.drawData.y0 = y0
+	td.calcY(arr.dstComp, y0+arr.drawData.height)
+}
+
+func tdCompLines(comp *Comp) int {
+	return comp.contentLines() + len(comp.notes)
+}
+
+// extendArrows lets all arrows end at their destination and returns the
+// maximum y value of all shapes.
+func (td *topDown) extendArrows() int {
+	ymax := 0
+	for _, arr := range td.arrows {
+		ad := arr.drawData
+		ad.height = arr.dstComp.getDrawData().y0 - ad.y0
+	}
+	for _, comp := range td.comps {
+		ymax = max(ymax, comp.getDrawData().ymax())
+	}
+	return ymax
+}
+
+// --------------------------------------------------------------------------
+// Calculate horizontal values of shapes (x0 and width)
+// --------------------------------------------------------------------------
+
+// calcX places comp and everything after it at x0 and returns the maximum
+// x value used.
+func (td *topDown) calcX(comp anyComp, x0 int) int {
+	switch c := comp.(type) {
+	case *StartPort:
+		td.placeText(c, c.drawData, x0, c.textWidth(c.name, false))
+		return max(c.drawData.xmax(), td.calcArrowX(c.output, x0))
+	case *BreakEnd:
+		td.placeText(c, c.drawData, x0, c.textWidth(BreakText+strconv.Itoa(c.number), false))
+		return max(c.drawData.xmax(), td.calcArrowX(c.output, x0))
+	case *Comp:
+		return td.calcCompX(c, x0)
+	case *EndPort:
+		td.placeText(c, c.drawData, x0, c.textWidth(c.name, false))
+		return c.drawData.xmax()
+	case *Loop:
+		td.placeText(c, c.drawData, x0, c.textWidth(tdLoopText(c), false))
+		return c.drawData.xmax()
+	case *BreakStart:
+		td.placeText(c, c.drawData, x0, c.textWidth(BreakText+strconv.Itoa(c.number), false))
+		return c.drawData.xmax()
+	default:
+		panic(fmt.Sprintf("unable to lay out unknown anyComp: %T", comp))
+	}
+}
+
+func (td *topDown) placeText(comp anyComp, d *drawData, x0, width int) {
+	d.x0 = x0
+	d.width = width
+	td.comps = append(td.comps, comp)
+}
+
+func (td *topDown) calcCompX(comp *Comp, x0 int) int {
+	cd := comp.drawData
+	if cd.width > 0 { // already placed, so we just widen it for this input
+		if x0 < cd.x0 { // the input comes from the left
+			cd.width += cd.x0 - x0
+			cd.x0 = x0
+			comp.calcPluginsWidth(x0, cd.width)
+		}
+		cd.width = max(cd.width, x0+tdArrowIndent+WordGap-cd.x0)
+		return x0 + tdArrowIndent + WordGap
+	}
+
+	td.comps = append(td.comps, comp)
+	cd.x0 = x0
+	cd.width = comp.calcPluginsWidth(x0, comp.calcMainWidth())
+	xmax := cd.xmax()
+	col := x0
+	for i, out := range comp.outputs {
+		if i > 0 {
+			col = xmax + tdColumnGap
+		}
+		cd.width = max(cd.width, col+tdArrowIndent+WordGap-x0)
+		xmax = max(xmax, cd.xmax(), td.calcArrowX(out, col))
+	}
+	return xmax
+}
+
+func (td *topDown) calcArrowX(arr *Arrow, x0 int) int {
+	td.arrows = append(td.arrows, arr)
+	ad := arr.drawData
+	ad.x0 = x0
+	for _, dt := range arr.dataTypes {
+		dt.drawData = &drawData{x0: x0}
+	}
+	arr.calcDataTypesWidth()
+	arr.notesWidth = notesWidth(arr.metrics, arr.notes)
+	labelWidth := max(arr.dataTypesWidth, arr.notesWidth-2*WordGap, arr.textWidth(arr.srcPort, true), arr.textWidth(arr.dstPort, true))
+	ad.width = tdArrowIndent + WordGap + labelWidth
+	return max(ad.xmax(), td.calcX(arr.dstComp, x0))
+}
+
+func tdLoopText(loop *Loop) string {
+	txt := BreakText + LoopText + loop.name
+	if loop.port != "" {
+		txt += ":" + loop.port
+	}
+	return txt
+}
+
+// --------------------------------------------------------------------------
+// Convert To SVG
+// --------------------------------------------------------------------------
+// tdCompToSVG adds the component to the diagram.
+// If md isn't nil, the links of the component are added to it.
+func tdCompToSVG(svg *svgFlow, md *mdFlow, comp anyComp) {
+	d := comp.getDrawData()
+	txt := &svgText{
+		X:     d.x0,
+		Y:     d.y0 + LineHeight - TextOffset,
+		Width: d.width,
+	}
+	switch c := comp.(type) {
+	case *StartPort:
+		txt.Text = c.name
+	case *EndPort:
+		txt.Text = c.name
+		txt.Error = c.input.isError
+	case *BreakStart:
+		txt.Text = BreakText + strconv.Itoa(c.number)
+	case *BreakEnd:
+		txt.Text = BreakText + strconv.Itoa(c.number)
+	case *Loop:
+		txt.Text = tdLoopText(c)
+		txt.Link = !c.goLink && c.link != ""
+		txt.GoLink = c.goLink
+		md.addLink(c.name, c.link, c.goLink)
+	case *Comp:
+		tdMainCompToSVG(svg, md, c)
+		return
+	}
+	svg.Texts = append(svg.Texts, txt)
+}
+
+func tdMainCompToSVG(svg *svgFlow, md *mdFlow, comp *Comp) {
+	cd := comp.drawData
+	cd.lines = tdCompLines(comp)
+	mainLines := 1
+	if comp.name != "" {
+		mainLines++
+	}
+	lines := mainLines
+	for _, p := range comp.plugins {
+		calcPluginVerticals(p, cd.y0+lines*LineHeight, lines)
+		lines += p.drawData.lines
+	}
+	comp.setPluginWidths(cd.width)
+	md.addLink(comp.typ, comp.link, comp.goLink)
+	for _, p := range comp.plugins {
+		for _, pt := range p.types {
+			md.addLink(pt.typ, pt.link, pt.goLink)
+		}
+	}
+
+	comp.shapeToSVG(svg, cd)
+	for line := 0; line < cd.lines; line++ {
+		if comp.mainToSVG(svg, nil, line) {
+			continue
+		}
+		for _, p := range comp.plugins {
+			if pluginGroupToSVG(svg, nil, line, FlowModeNoLinks, comp.metrics, p) {
+				break
+			}
+		}
+		comp.notesToSVG(svg, line)
+	}
+}
+
+// tdArrowToSVG adds the arrow to the diagram.
+// If md isn't nil, the links of its data types are added to it.
+func tdArrowToSVG(svg *svgFlow, md *mdFlow, arr *Arrow) {
+	ad := arr.drawData
+	x := ad.x0 + tdArrowIndent
+	svg.Arrows = append(svg.Arrows, &svgArrow{
+		X1:     x,
+		Y1:     ad.y0,
+		X2:     x,
+		Y2:     ad.ymax(),
+		XTip1:  x - arrTipHeight,
+		YTip1:  ad.ymax() - arrTipHeight,
+		XTip2:  x + arrTipHeight,
+		YTip2:  ad.ymax() - arrTipHeight,
+		Error:  arr.isError,
+		Change: arr.change,
+	})
+
+	y := ad.y0
+	x += WordGap
+	if arr.srcPort != "" {
+		svg.Texts = append(svg.Texts, &svgText{
+			X:     x,
+			Y:     y + LineHeight - TextOffset,
+			Width: arr.textWidth(arr.srcPort, true),
+			Text:  arr.srcPort,
+			Small: true,
+		})
+		y += LineHeight
+	}
+	for _, note := range arr.notes {
+		noteToSVG(svg, arr.metrics, x-WordGap, y, note)
+		y += LineHeight
+	}
+	lastIdx := len(arr.dataTypes) - 1
+	for i, dt := range arr.dataTypes {
+		md.addDataTypeLink(dt.typ, dt.link)
+		dt.drawData.y0 = y
+		arrowDataTypeToSVG(svg, nil, arr.metrics, dt, x-CharWidth, arr.dataTypesWidth, arr.dataTypesWidth,
+			i == 0, i == lastIdx)
+		y += LineHeight
+	}
+	if arr.dstPort != "" {
+		svg.Texts = append(svg.Texts, &svgText{
+			X:     x,
+			Y:     ad.ymax() - LineHeight/2 - TextOffset,
+			Width: arr.textWidth(arr.dstPort, true),
+			Text:  arr.dstPort,
+			Small: true,
+		})
+	}
+}
+
+// addLink lists the link of a component, plugin or loop below the diagram.
+// It does nothing for a nil md or an empty link.
+func (md *mdFlow) addLink(name, link string, goLink bool) {
+	switch {
+	case md == nil || link == "":
+	case goLink:
+		md.GoFuncs[name] = link
+	default:
+		md.Subflows[name] = link
+	}
+}
+
+// addDataTypeLink lists the link of a data type below the diagram.
+// It does nothing for a nil md or an empty link.
+func (md *mdFlow) addDataTypeLink(typ, link string) {
+	if md != nil && link != "" {
+		md.DataTypes[typ] = link
+	}
+}
@@ -0,0 +1,241 @@
+package draw
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrEmptyFlow is returned for a flow without any start.
+var ErrEmptyFlow = errors.New("nothing to draw in the flow")
+
+// ValidationError contains all problems found in a flow.
+// The single problems can be found with errors.As.
+type ValidationError struct {
+	Flow   string
+	Errors []error
+}
+
+func (ve *ValidationError) Error() string {
+	msgs := make([]string, len(ve.Errors))
+	for i, err := range ve.Errors {
+		msgs[i] = "\t" + err.Error()
+	}
+	return fmt.Sprintf("invalid flow %q:\n%s", ve.Flow, strings.Join(msgs, "\n"))
+}
+
+func (ve *ValidationError) Unwrap() []error {
+	return ve.Errors
+}
+
+// DanglingArrowError is an arrow that misses its source or destination.
+// If Output is true, the arrow goes out of Comp and misses its
+// destination. Else it goes into Comp and misses its source.
+type DanglingArrowError struct {
+	Comp   string
+	Port   string
+	Output bool
+}
+
+func (e *DanglingArrowError) Error() string {
+	if e.Output {
+		return fmt.Sprintf("arrow from port %q of %s has got no destination", e.Port, e.Comp)
+	}
+	return fmt.Sprintf("arrow to port %q of %s has got no source", e.Port, e.Comp)
+}
+
+// CycleError is a cycle of arrows that isn't drawn with a Loop.
+// Comps contains the components of the cycle in order. The first one is
+// repeated at the end.
+type CycleError struct {
+	Comps []string
+}
+
+func (e *CycleError) Error() string {
+	return "cycle without a loop: " + strings.Join(e.Comps, " -> ")
+}
+
+// DuplicateIDError is a component with the same ID as another component
+// in the registry of the flow.
+type DuplicateIDError struct {
+	ID string
+}
+
+func (e *DuplicateIDError) Error() string {
+	return fmt.Sprintf("duplicate component with ID: %q", e.ID)
+}
+
+// UnreachableError is a component in the registry of the flow that isn't
+// connected to any start of the flow.
+type UnreachableError struct {
+	ID string
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("component with ID %q isn't connected to any start of the flow", e.ID)
+}
+
+// Validate checks that the flow can be laid out.
+// It returns ErrEmptyFlow for a flow without starts.
+// Otherwise all problems found are returned in a *ValidationError.
+func (flow *Flow) Validate() error {
+	if len(flow.starts) == 0 {
+		return ErrEmptyFlow
+	}
+	v := &validator{
+		reached: make(map[anyComp]bool, 2*len(flow.compRegistry)),
+		state:   make(map[anyComp]int, 2*len(flow.compRegistry)),
+	}
+	for _, id := range flow.duplicateIDs {
+		v.errs = append(v.errs, &DuplicateIDError{ID: id})
+	}
+	for _, start := range flow.starts {
+		v.reach(start)
+	}
+	for _, comp := range v.order {
+		v.findCycles(comp)
+	}
+	unreached := make([]string, 0, 8)
+	for id, comp := range flow.compRegistry {
+		if !v.reached[comp] {
+			unreached = append(unreached, id)
+		}
+	}
+	for _, g := range flow.groups {
+		for _, comp := range g.comps {
+			if !v.reached[comp] && flow.compRegistry[comp.ID()] != comp {
+				unreached = append(unreached, comp.ID())
+			}
+		}
+	}
+	sort.Strings(unreached)
+	for _, id := range unreached {
+		v.errs = append(v.errs, &UnreachableError{ID: id})
+	}
+
+	if len(v.errs) > 0 {
+		return &ValidationError{Flow: flow.name, Errors: v.errs}
+	}
+	return nil
+}
+
+const (
+	visiting = iota + 1
+	visited
+)
+
+type validator struct {
+	errs    []error
+	reached map[anyComp]bool
+	order   []anyComp // of reaching the components
+	state   map[anyComp]int
+	path    []anyComp
+}
+
+// reach marks all components connected to comp and finds dangling arrows.
+func (v *validator) reach(comp anyComp) {
+	if comp == nil || v.reached[comp] {
+		return
+	}
+	v.reached[comp] = true
+	v.order = append(v.order, comp)
+	for _, out := range outputsOf(comp) {
+		if out == nil || out.dstComp == nil {
+			v.errs = append(v.errs, &DanglingArrowError{Comp: compDescription(comp), Port: arrowPort(out, true), Output: true})
+			continue
+		}
+		v.reach(out.dstComp)
+	}
+	for _, in := range inputsOf(comp) {
+		if in == nil || in.srcComp == nil {
+			v.errs = append(v.errs, &DanglingArrowError{Comp: compDescription(comp), Port: arrowPort(in, false)})
+			continue
+		}
+		v.reach(in.srcComp)
+	}
+	if brk, ok := comp.(*BreakStart); ok && brk.end != nil {
+		v.reach(brk.end)
+	}
+}
+
+// findCycles follows the arrows from comp and reports every cycle found.
+func (v *validator) findCycles(comp anyComp) {
+	if comp == nil {
+		return
+	}
+	switch v.state[comp] {
+	case visited:
+		return
+	case visiting:
+		i := len(v.path) - 1
+		for v.path[i] != comp {
+			i--
+		}
+		names := make([]string, 0, len(v.path)-i+1)
+		for _, c := range v.path[i:] {
+			names = append(names, compDescription(c))
+		}
+		v.errs = append(v.errs, &CycleError{Comps: append(names, compDescription(comp))})
+		return
+	}
+	v.state[comp] = visiting
+	v.path = append(v.path, comp)
+	for _, out := range outputsOf(comp) {
+		if out != nil {
+			v.findCycles(out.dstComp)
+		}
+	}
+	if brk, ok := comp.(*BreakStart); ok && brk.end != nil {
+		v.findCycles(brk.end)
+	}
+	v.path = v.path[:len(v.path)-1]
+	v.state[comp] = visited
+}
+
+func inputsOf(comp anyComp) []*Arrow {
+	switch c := comp.(type) {
+	case *Comp:
+		return c.inputs
+	case *EndPort:
+		return []*Arrow{c.input}
+	case *Loop:
+		return []*Arrow{c.input}
+	case *BreakStart:
+		return []*Arrow{c.input}
+	default:
+		return nil
+	}
+}
+
+func arrowPort(arr *Arrow, output bool) string {
+	switch {
+	case arr == nil:
+		return ""
+	case output:
+		return arr.srcPort
+	default:
+		return arr.dstPort
+	}
+}
+
+// compDescription returns a short description of the component for error
+// messages.
+func compDescription(comp anyComp) string {
+	switch c := comp.(type) {
+	case *Comp:
+		return strconv.Quote(c.ID())
+	case *StartPort:
+		return "start port " + strconv.Quote(c.name)
+	case *EndPort:
+		return "end port " + strconv.Quote(c.name)
+	case *Loop:
+		return "loop to " + strconv.Quote(c.name)
+	case *BreakStart:
+		return "break " + strconv.Itoa(c.number)
+	case *BreakEnd:
+		return "break " + strconv.Itoa(c.number)
+	}
+	return fmt.Sprintf("%T", comp)
+}
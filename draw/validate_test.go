@@ -0,0 +1,134 @@
+package draw_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+)
+
+func TestValidate(t *testing.T) {
+	specs := []struct {
+		name          string
+		givenFlow     func() *draw.Flow
+		expectedError string
+		expectedType  func(error) bool
+	}{
+		{
+			name:      "valid",
+			givenFlow: buildSmallTestFlow,
+		}, {
+			name:      "big",
+			givenFlow: buildBigTestFlowData,
+		}, {
+			name: "empty",
+			givenFlow: func() *draw.Flow {
+				return draw.NewFlow("empty", draw.FlowModeNoLinks, 1500, nil)
+			},
+			expectedError: "nothing to draw in the flow",
+		}, {
+			name: "dangling",
+			givenFlow: func() *draw.Flow {
+				flow := draw.NewFlow("dangling", draw.FlowModeNoLinks, 1500, nil)
+				return flow.AddStart(draw.NewStartPort("in").AddOutput(
+					draw.NewArrow("", "").AddDestination(
+						draw.NewComp("a", "A", "", flow).AddOutput(draw.NewArrow("out", "")),
+					),
+				))
+			},
+			expectedError: `arrow from port "out" of "a" has got no destination`,
+			expectedType:  func(err error) bool { var e *draw.DanglingArrowError; return errors.As(err, &e) },
+		}, {
+			name: "cycle",
+			givenFlow: func() *draw.Flow {
+				flow := draw.NewFlow("cycle", draw.FlowModeNoLinks, 1500, nil)
+				return flow.AddStart(draw.NewStartPort("in").AddOutput(
+					draw.NewArrow("", "").AddDestination(
+						draw.NewComp("a", "A", "", flow).AddOutput(
+							draw.NewArrow("", "").AddDestination(
+								draw.NewComp("b", "B", "", flow).AddOutput(
+									draw.NewArrow("again", "").MustLinkComp("a", flow),
+								),
+							),
+						),
+					),
+				))
+			},
+			expectedError: `cycle without a loop: "a" -> "b" -> "a"`,
+			expectedType:  func(err error) bool { var e *draw.CycleError; return errors.As(err, &e) },
+		}, {
+			name: "duplicate",
+			givenFlow: func() *draw.Flow {
+				flow := draw.NewFlow("duplicate", draw.FlowModeNoLinks, 1500, nil)
+				return flow.AddStart(draw.NewStartPort("in").AddOutput(
+					draw.NewArrow("", "").AddDestination(
+						draw.NewComp("a", "A", "", flow).AddOutput(
+							draw.NewArrow("", "").AddDestination(
+								draw.NewComp("a", "B", "", flow).AddOutput(
+									draw.NewArrow("", "").AddDestination(draw.NewEndPort("out")),
+								),
+							),
+						),
+					),
+				))
+			},
+			expectedError: `duplicate component with ID: "a"`,
+			expectedType:  func(err error) bool { var e *draw.DuplicateIDError; return errors.As(err, &e) },
+		}, {
+			name: "unreachable",
+			givenFlow: func() *draw.Flow {
+				flow := buildSmallTestFlow()
+				draw.NewComp("lonely", "Lonely", "", flow)
+				return flow
+			},
+			expectedError: `component with ID "lonely" isn't connected to any start of the flow`,
+			expectedType:  func(err error) bool { var e *draw.UnreachableError; return errors.As(err, &e) },
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			flow := spec.givenFlow()
+			err := flow.Validate()
+			if spec.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), spec.expectedError) {
+				t.Fatalf("expected error containing %q, got: %v", spec.expectedError, err)
+			}
+			if spec.expectedType != nil && !spec.expectedType(err) {
+				t.Errorf("expected error of the right type, got: %#v", err)
+			}
+			if _, _, drawErr := flow.Draw(); drawErr == nil || drawErr.Error() != err.Error() {
+				t.Errorf("expected Draw to fail with %q, got: %v", err, drawErr)
+			}
+		})
+	}
+}
+
+func TestValidateTypes(t *testing.T) {
+	err := draw.NewFlow("empty", draw.FlowModeNoLinks, 1500, nil).Validate()
+	if !errors.Is(err, draw.ErrEmptyFlow) {
+		t.Errorf("expected ErrEmptyFlow, got: %v", err)
+	}
+
+	flow := draw.NewFlow("cycle", draw.FlowModeNoLinks, 1500, nil)
+	flow.AddStart(draw.NewComp("a", "A", "", flow).AddOutput(
+		draw.NewArrow("", "").MustLinkComp("a", flow),
+	))
+	var cycle *draw.CycleError
+	if err := flow.Validate(); !errors.As(err, &cycle) {
+		t.Fatalf("expected a cycle error, got: %v", err)
+	}
+	if got := strings.Join(cycle.Comps, " "); got != `"a" "a"` {
+		t.Errorf("expected cycle of a, got: %s", got)
+	}
+	var verr *draw.ValidationError
+	if !errors.As(flow.Validate(), &verr) || verr.Flow != "cycle" || len(verr.Errors) != 1 {
+		t.Errorf("expected validation error with one problem, got: %#v", verr)
+	}
+}
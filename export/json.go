@@ -0,0 +1,275 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"path/filepath"
+
+	"github.com/flowdev/ea-flow-doc/flow/base"
+)
+
+// JSONVersion is the version of the JSON schema written by JSON.
+// It is increased for every incompatible change of the schema.
+// Optional fields added later don't change it, so consumers have to ignore
+// unknown fields. Added to version 1 were:
+//   - "doc" of flows, ports and data
+//   - "methods" and "implementations" of plugins
+//   - "notes" and "group" of steps
+const JSONVersion = 1
+
+// Step kinds used in the JSON schema.
+const (
+	StepKindCall   = "call"
+	StepKindReturn = "return"
+	StepKindBranch = "branch"
+)
+
+// Doc is the root of the JSON schema.
+type Doc struct {
+	Version int     `json:"version"`
+	Flows   []*Flow `json:"flows"`
+}
+
+// Flow is the JSON representation of a single flow.
+type Flow struct {
+	Package    string    `json:"package"`
+	Name       string    `json:"name"`
+	Pos        *Position `json:"pos,omitempty"`
+	Doc        string    `json:"doc,omitempty"`
+	InPort     Port      `json:"inPort"`
+	Inputs     []Data    `json:"inputs"`
+	Plugins    []Plugin  `json:"plugins"`
+	OutPorts   []Port    `json:"outPorts"`
+	MainBranch *Branch   `json:"mainBranch"`
+}
+
+// Port is the JSON representation of a flow port.
+type Port struct {
+	Name     string    `json:"name"`
+	Implicit bool      `json:"implicit,omitempty"`
+	Error    bool      `json:"error,omitempty"`
+	Pos      *Position `json:"pos,omitempty"`
+	Doc      string    `json:"doc,omitempty"`
+}
+
+// Plugin is the JSON representation of a plugin.
+// Methods is the method set of its interface type and Implementations are
+// the Go types of the project that implement it.
+type Plugin struct {
+	Data
+	Methods         []string `json:"methods,omitempty"`
+	Implementations []string `json:"implementations,omitempty"`
+}
+
+// Data is the JSON representation of a data declaration.
+// Type is the flow data type and GoType the resolved Go type.
+type Data struct {
+	Name    string    `json:"name,omitempty"`
+	Type    string    `json:"type"`
+	GoType  string    `json:"goType,omitempty"`
+	Pos     *Position `json:"pos,omitempty"`
+	TypePos *Position `json:"typePos,omitempty"`
+	Doc     string    `json:"doc,omitempty"`
+}
+
+// Branch is the JSON representation of a control flow branch.
+// Types maps the names of all data known in the branch to their types.
+type Branch struct {
+	Pos   *Position         `json:"pos,omitempty"`
+	Types map[string]string `json:"types,omitempty"`
+	Steps []*Step           `json:"steps"`
+}
+
+// Step is the JSON representation of a step in a flow.
+// Kind is one of StepKindCall, StepKindReturn or StepKindBranch and
+// decides which of the other fields are filled.
+type Step struct {
+	Kind      string    `json:"kind"`
+	Pos       *Position `json:"pos,omitempty"`
+	Component string    `json:"component,omitempty"`
+	InPort    *Port     `json:"inPort,omitempty"`
+	Inputs    []string  `json:"inputs,omitempty"`
+	Outputs   []string  `json:"outputs,omitempty"`
+	OutPort   *Port     `json:"outPort,omitempty"`
+	Datas     []string  `json:"datas,omitempty"`
+	Notes     []string  `json:"notes,omitempty"`
+	Group     string    `json:"group,omitempty"`
+	Branch    *Branch   `json:"branch,omitempty"`
+}
+
+// Position is a position in a Go source file.
+// File is relative to the root directory given to JSON if possible.
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// JSON returns the indented JSON representation of the given flows.
+// File names in source positions are made relative to root
+// unless root is empty.
+func JSON(flowDatas []*base.FlowData, root string) ([]byte, error) {
+	doc, err := NewDoc(flowDatas, root)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal flows to JSON: %w", err)
+	}
+	return append(bs, '\n'), nil
+}
+
+// NewDoc converts the given flows to their JSON representation.
+// If a flow contains a step that isn't part of the schema, an error is
+// returned.
+func NewDoc(flowDatas []*base.FlowData, root string) (*Doc, error) {
+	doc := &Doc{
+		Version: JSONVersion,
+		Flows:   make([]*Flow, 0, len(flowDatas)),
+	}
+	for _, fd := range flowDatas {
+		f, err := newFlow(fd, root)
+		if err != nil {
+			return nil, fmt.Errorf("unable to export flow %s.%s: %w", fd.PkgPath, fd.ComponentName, err)
+		}
+		doc.Flows = append(doc.Flows, f)
+	}
+	return doc, nil
+}
+
+func newFlow(fd *base.FlowData, root string) (*Flow, error) {
+	cv := converter{fset: fd.Fset, root: root}
+	mainBranch, err := cv.branch(fd.MainBranch)
+	if err != nil {
+		return nil, err
+	}
+	f := &Flow{
+		Package:    fd.PkgPath,
+		Name:       fd.ComponentName,
+		Pos:        cv.position(fd.Pos),
+		Doc:        fd.Doc,
+		InPort:     cv.port(fd.InPort),
+		Inputs:     make([]Data, 0, len(fd.Inputs)),
+		Plugins:    make([]Plugin, 0, len(fd.Plugins)),
+		OutPorts:   make([]Port, 0, len(fd.OutPorts)),
+		MainBranch: mainBranch,
+	}
+	for _, dat := range fd.Inputs {
+		f.Inputs = append(f.Inputs, cv.data(dat))
+	}
+	for _, p := range fd.Plugins {
+		f.Plugins = append(f.Plugins, Plugin{
+			Data:            cv.data(p.DataTyp),
+			Methods:         p.Methods,
+			Implementations: p.Implementations,
+		})
+	}
+	for _, p := range fd.OutPorts {
+		f.OutPorts = append(f.OutPorts, cv.port(p))
+	}
+	return f, nil
+}
+
+type converter struct {
+	fset *token.FileSet
+	root string
+}
+
+func (cv converter) position(pos token.Pos) *Position {
+	if cv.fset == nil || !pos.IsValid() {
+		return nil
+	}
+	p := cv.fset.Position(pos)
+	file := p.Filename
+	if cv.root != "" {
+		if rel, err := filepath.Rel(cv.root, file); err == nil {
+			file = filepath.ToSlash(rel)
+		}
+	}
+	return &Position{File: file, Line: p.Line, Column: p.Column}
+}
+
+func (cv converter) port(p base.Port) Port {
+	return Port{
+		Name:     p.Name,
+		Implicit: p.IsImplicit,
+		Error:    p.IsError,
+		Pos:      cv.position(p.Pos),
+		Doc:      p.Doc,
+	}
+}
+
+func (cv converter) data(dat base.DataTyp) Data {
+	return Data{
+		Name:    dat.Name,
+		Type:    dat.Typ,
+		GoType:  dat.GoTyp,
+		Pos:     cv.position(dat.NamePos),
+		TypePos: cv.position(dat.TypPos),
+		Doc:     dat.Doc,
+	}
+}
+
+func (cv converter) branch(b *base.Branch) (*Branch, error) {
+	if b == nil {
+		return nil, nil
+	}
+	jb := &Branch{
+		Pos:   cv.position(b.Pos),
+		Steps: make([]*Step, 0, len(b.Steps)),
+	}
+	if len(b.DataMap) > 0 {
+		jb.Types = make(map[string]string, len(b.DataMap))
+		for name, typ := range b.DataMap {
+			jb.Types[name] = typ
+		}
+	}
+	for _, step := range b.Steps {
+		js, err := cv.step(step)
+		if err != nil {
+			return nil, err
+		}
+		jb.Steps = append(jb.Steps, js)
+	}
+	return jb, nil
+}
+
+func (cv converter) step(step base.Step) (*Step, error) {
+	switch s := step.(type) {
+	case *base.CallStep:
+		inPort := cv.port(s.InPort)
+		return &Step{
+			Kind:      StepKindCall,
+			Pos:       cv.position(s.Pos),
+			Component: s.ComponentName,
+			InPort:    &inPort,
+			Inputs:    s.Inputs,
+			Outputs:   s.Outputs,
+			Notes:     s.Notes,
+			Group:     s.Group,
+		}, nil
+	case *base.ReturnStep:
+		outPort := cv.port(s.OutPort)
+		return &Step{
+			Kind:    StepKindReturn,
+			Pos:     cv.position(s.Pos),
+			OutPort: &outPort,
+			Datas:   s.Datas,
+			Notes:   s.Notes,
+		}, nil
+	case *base.Branch:
+		b, err := cv.branch(s)
+		if err != nil {
+			return nil, err
+		}
+		return &Step{
+			Kind:   StepKindBranch,
+			Pos:    cv.position(s.Pos),
+			Branch: b,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unable to export unknown step type: %T", step)
+	}
+}
@@ -0,0 +1,34 @@
+package export_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/export"
+	"github.com/flowdev/ea-flow-doc/flow/base"
+)
+
+// unknownStep is a step the schema doesn't know.
+type unknownStep struct {
+	*base.CallStep
+}
+
+func TestJSONUnknownStep(t *testing.T) {
+	fd := &base.FlowData{
+		PkgPath:       "example.com/project/orders",
+		ComponentName: "ProcessOrder",
+		MainBranch: &base.Branch{Steps: []base.Step{
+			&base.Branch{Steps: []base.Step{unknownStep{&base.CallStep{}}}},
+		}},
+	}
+
+	_, err := export.JSON([]*base.FlowData{fd}, "")
+	if err == nil {
+		t.Fatal("expected an error for the unknown step, got none")
+	}
+	for _, expected := range []string{"example.com/project/orders.ProcessOrder", "export_test.unknownStep"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got: %v", expected, err)
+		}
+	}
+}
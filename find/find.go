@@ -11,10 +11,14 @@ import (
 )
 
 // PackageFuncs contains all marked functions from parsing a package.
+// Comments contains the comments inside of the marked functions (e.g. of
+// parameters and results) by the node they belong to.
 type PackageFuncs struct {
+	PkgPath   string
 	Fset      *token.FileSet
 	TypesInfo *types.Info
 	Funcs     []*ast.FuncDecl
+	Comments  ast.CommentMap
 }
 
 // FlowFuncs finds FlowDev flows in the given packages and returns the
@@ -54,14 +58,31 @@ func markedFuncsFromPackage(pkg *packages.Package, mark string, searchProd, sear
 		}
 	}
 
-	pkgFunc := PackageFuncs{Fset: pkg.Fset, TypesInfo: pkg.TypesInfo, Funcs: make([]*ast.FuncDecl, 0, 1024)}
+	pkgFunc := PackageFuncs{
+		PkgPath: pkg.PkgPath, Fset: pkg.Fset, TypesInfo: pkg.TypesInfo,
+		Funcs: make([]*ast.FuncDecl, 0, 1024), Comments: make(ast.CommentMap, 256),
+	}
 	for _, astf := range pkg.Syntax {
+		n := len(pkgFunc.Funcs)
 		pkgFunc.Funcs = addMarkedFuncsFromFile(pkgFunc.Funcs, astf, mark)
+		if len(pkgFunc.Funcs) > n {
+			addComments(pkgFunc.Comments, pkg.Fset, astf, pkgFunc.Funcs[n:])
+		}
 	}
 	//fmt.Println("TYPEs1:", pkg.TypesInfo)
 	return pkgFunc
 }
 
+// addComments adds the comments of the functions in the file to comments.
+func addComments(comments ast.CommentMap, fset *token.FileSet, astf *ast.File, funcs []*ast.FuncDecl) {
+	cmap := ast.NewCommentMap(fset, astf, astf.Comments)
+	for _, fun := range funcs {
+		for node, cgs := range cmap.Filter(fun) {
+			comments[node] = cgs
+		}
+	}
+}
+
 func addMarkedFuncsFromFile(funcs []*ast.FuncDecl, astf *ast.File, mark string) []*ast.FuncDecl {
 	for _, decl := range astf.Decls {
 		funcs = addMarkedFuncFromDecl(funcs, decl, mark)
@@ -11,34 +11,65 @@ import (
 const PortPrefix = "port"
 
 // Port is a full description of a flow port.
+// Doc is the comment of the result that defines the port (if any).
 type Port struct {
 	Name       string
 	Pos        token.Pos
 	IsImplicit bool
 	IsError    bool
+	Doc        string
 }
 
 // DataTyp describes a data declaration with name and type.
+// Typ is the flow data type and GoTyp the resolved Go type.
+// Doc is the comment of the parameter or result (if any).
 type DataTyp struct {
 	Name    string
 	NamePos token.Pos
 	Typ     string
+	GoTyp   string
 	TypPos  token.Pos
+	Doc     string
+}
+
+// Plugin is a plugin parameter of a flow. Plugins are recognized by the
+// name prefix 'plugin' and come after all other parameters.
+// Interface is the resolved interface type of the plugin or nil if its
+// type isn't an interface. Methods contains the method set of the
+// interface (e.g. 'Save(*orders.Order) error') and Implementations the
+// types of the project that implement it (see flow.AddImplementations).
+type Plugin struct {
+	DataTyp
+	Interface       *types.Interface
+	Methods         []string
+	Implementations []string
 }
 
 // CallStep is a step in a flow that performs a call to a component.
+// PkgPath is the path of the package of the called function. It is empty
+// if the function can't be resolved (e.g. for method calls).
+// Notes and Group are set with the directives '//flowdev:note <text>' and
+// '//flowdev:group <name>' in the comments of the statement.
 type CallStep struct {
+	Pos           token.Pos
 	Inputs        []string
 	InPort        Port
 	ComponentName string
+	PkgPath       string
 	Outputs       []string
+	Notes         []string
+	Group         string
 }
 
 // ReturnStep is a step in a flow that ends the flow and sends data to an
 // output port.
+// Notes are set with the directive '//flowdev:note <text>' in the comments
+// of the return statement.
 type ReturnStep struct {
+	Pos     token.Pos
 	Datas   []string
 	OutPort Port
+	Notes   []string
 }
 
 // Step is a step in a flow. It can be one of: CallStep, ReturnStep or Branch
@@ -49,6 +80,7 @@ type Step interface {
 // Branch is a control flow branch. It can be either the main branch of a flow
 // or sub-branch created by an if statement.
 type Branch struct {
+	Pos     token.Pos
 	DataMap map[string]string
 	Steps   []Step
 	Parent  *Branch
@@ -59,9 +91,18 @@ type Branch struct {
 // The main branch always starts with the first call expression of the function.
 // Sub-branches are created with if expressions.
 // Consequently a flow can't start with an if expression!
+// Doc is the doc comment of the flow function without directives like
+// the flowdev:flow marker.
+// Inputs are the data parameters of the flow and Plugins the plugin
+// parameters after them.
 type FlowData struct {
+	PkgPath       string
+	Fset          *token.FileSet
+	Pos           token.Pos
+	Doc           string
 	InPort        Port
 	Inputs        []DataTyp
+	Plugins       []Plugin
 	ComponentName string
 	OutPorts      []Port
 	MainBranch    *Branch
@@ -117,6 +158,17 @@ func (fd *FlowData) String() string {
 	}
 	sb.WriteString("\n")
 
+	sb.WriteString("    Plugins: ")
+	for i, p := range fd.Plugins {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(p.Name)
+		sb.WriteString(" ")
+		sb.WriteString(p.Typ)
+	}
+	sb.WriteString("\n")
+
 	sb.WriteString("    ComponentName: ")
 	sb.WriteString(fd.ComponentName)
 	sb.WriteString("\n")
@@ -217,6 +269,14 @@ func AddDataToMap(name, typ string, m map[string]string) map[string]string {
 	return m
 }
 
+// IsPlugin returns true if the given data is a plugin of a flow.
+// Plugins are recognized by the name prefix 'plugin'.
+func IsPlugin(dat DataTyp) bool {
+	const prefixPlugin = "plugin"
+	return strings.HasPrefix(dat.Name, prefixPlugin) &&
+		(len(dat.Name) > len(prefixPlugin))
+}
+
 // IsBoring returns true for builtin types.
 func IsBoring(typ string) bool {
 	switch typ { // simple builtin types are 'boring'
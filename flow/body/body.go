@@ -7,6 +7,7 @@ import (
 	"go/token"
 	"go/types"
 	"log"
+	"strings"
 
 	"github.com/flowdev/ea-flow-doc/data"
 	"github.com/flowdev/ea-flow-doc/flow/base"
@@ -25,23 +26,61 @@ const (
 	identTypeOnlyNil
 )
 
+// Directives in the comments of flow statements.
+const (
+	noteDirective  = "//flowdev:note "
+	groupDirective = "//flowdev:group "
+)
+
 // ParseFuncBody parses a flow function body.
+// The comments are used for the notes and groups of the steps (they may be
+// nil).
 func ParseFuncBody(
 	body *ast.BlockStmt,
-	fset *token.FileSet, typesInfo *types.Info,
+	fset *token.FileSet, typesInfo *types.Info, comments ast.CommentMap,
 	flowDat *base.FlowData, branch *base.Branch,
 	errs []error,
 ) []error {
 
 	for _, stmt := range body.List {
-		branch, errs = parseFuncStmt(stmt, fset, typesInfo, flowDat, branch, errs)
+		b, n := branch, len(branch.Steps)
+		branch, errs = parseFuncStmt(stmt, fset, typesInfo, comments, flowDat, branch, errs)
+		addDirectives(b.Steps[n:], comments[stmt])
 	}
 	return errs
 }
 
+// addDirectives adds the notes and groups found in the comments of a
+// statement to the steps created for it.
+func addDirectives(steps []base.Step, cgs []*ast.CommentGroup) {
+	notes, group := make([]string, 0, 2), ""
+	for _, cg := range cgs {
+		for _, c := range cg.List {
+			switch {
+			case strings.HasPrefix(c.Text, noteDirective):
+				notes = append(notes, strings.TrimSpace(c.Text[len(noteDirective):]))
+			case strings.HasPrefix(c.Text, groupDirective):
+				group = strings.TrimSpace(c.Text[len(groupDirective):])
+			}
+		}
+	}
+	if len(notes) == 0 && group == "" {
+		return
+	}
+	for _, step := range steps {
+		switch s := step.(type) {
+		case *base.CallStep:
+			s.Notes = append(s.Notes, notes...)
+			s.Group = group
+		case *base.ReturnStep:
+			s.Notes = append(s.Notes, notes...)
+		}
+	}
+}
+
 func parseFuncStmt(
 	stmt ast.Stmt,
-	fset *token.FileSet, typesInfo *types.Info,
+	fset *token.FileSet, typesInfo *types.Info, comments ast.CommentMap,
 	flowDat *base.FlowData, branch *base.Branch,
 	errs []error,
 ) (*base.Branch, []error) {
@@ -55,7 +94,7 @@ func parseFuncStmt(
 		errs = parseDecl(s.Decl, fset, typesInfo, branch, errs)
 	case *ast.ExprStmt:
 		var call *base.CallStep
-		call, errs = parseCall(s.X, false, fset, errs)
+		call, errs = parseCall(s.X, false, fset, typesInfo, errs)
 		if call != nil {
 			branch.Steps = append(branch.Steps, call)
 		}
@@ -63,7 +102,7 @@ func parseFuncStmt(
 		errs = parseAssignLHS(s.Lhs, fset, branch, errs)
 		if len(s.Rhs) == 1 {
 			var call *base.CallStep
-			call, errs = parseCall(s.Rhs[0], true, fset, errs)
+			call, errs = parseCall(s.Rhs[0], true, fset, typesInfo, errs)
 			if call != nil {
 				branch.Steps = append(branch.Steps, call)
 			}
@@ -76,7 +115,7 @@ func parseFuncStmt(
 			branch = branch.Parent
 		}
 	case *ast.IfStmt:
-		branch, errs = parseIf(s, fset, typesInfo, flowDat, branch, errs)
+		branch, errs = parseIf(s, fset, typesInfo, comments, flowDat, branch, errs)
 	case *ast.ForStmt,
 		*ast.RangeStmt,
 		*ast.BlockStmt,
@@ -174,7 +213,7 @@ func parseGenDecl(dcl *ast.GenDecl, fset *token.FileSet, typesInfo *types.Info,
 
 func parseCall(
 	expr ast.Expr, allowLiteral bool,
-	fset *token.FileSet,
+	fset *token.FileSet, typesInfo *types.Info,
 	errs []error,
 ) (*base.CallStep, []error) {
 
@@ -193,7 +232,7 @@ func parseCall(
 
 	switch e := expr.(type) {
 	case *ast.CallExpr:
-		call = &base.CallStep{}
+		call = &base.CallStep{Pos: e.Pos()}
 		// check function name:
 		var funcNameID *ast.Ident
 		pkg := ""
@@ -203,6 +242,7 @@ func parseCall(
 			if pkg != "" {
 				call.ComponentName = pkg + "." + call.ComponentName
 			}
+			call.PkgPath = calledPkgPath(funcNameID, typesInfo)
 		}
 		call.Inputs, errs = getFunctionArguments(e.Args, fset, errs)
 	case *ast.BasicLit:
@@ -236,6 +276,19 @@ func parseCall(
 	return call, errs
 }
 
+// calledPkgPath returns the path of the package of the called function or
+// "" if it can't be resolved.
+func calledPkgPath(funcNameID *ast.Ident, typesInfo *types.Info) string {
+	if typesInfo == nil {
+		return ""
+	}
+	fn, ok := typesInfo.Uses[funcNameID].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Signature().Recv() != nil {
+		return ""
+	}
+	return fn.Pkg().Path()
+}
+
 func getFunctionNameID(expr ast.Expr, fset *token.FileSet, errs []error,
 ) (string, *ast.Ident, []error) {
 
@@ -493,7 +546,7 @@ func parseExplicitPort(
 ) (done bool, errs2 []error) {
 	name := ""
 	name, errs = parseIdent(result, identTypeOrNil, fset, "name in return statement", errs)
-	if name != identNameError {
+	if name != identNameError && name != "nil" { // nil doesn't send to a port
 		if found {
 			errs = append(errs, errors.New(fset.Position(result.Pos()).String()+
 				fmt.Sprintf(
@@ -505,6 +558,7 @@ func parseExplicitPort(
 		}
 		branch.Steps = append(branch.Steps,
 			&base.ReturnStep{
+				Pos:     result.Pos(),
 				Datas:   []string{dataForName(name, branch.DataMap, globalData)},
 				OutPort: op,
 			})
@@ -523,6 +577,9 @@ func parseImplicitOutPort(
 
 	name := ""
 	rs := &base.ReturnStep{Datas: make([]string, 0, len(results)), OutPort: op}
+	if len(results) > 0 {
+		rs.Pos = results[0].Pos()
+	}
 	for _, result := range results {
 		name, errs = parseIdent(result, identTypeOrNil, fset, "name in return statement", errs)
 		if name != identNameError {
@@ -535,7 +592,7 @@ func parseImplicitOutPort(
 
 func parseIf(
 	ifs *ast.IfStmt,
-	fset *token.FileSet, typesInfo *types.Info,
+	fset *token.FileSet, typesInfo *types.Info, comments ast.CommentMap,
 	flowDat *base.FlowData, branch *base.Branch,
 	errs []error,
 ) (*base.Branch, []error) {
@@ -546,8 +603,9 @@ func parseIf(
 	}
 	errs = parseIfCond(ifs.Cond, fset, errs)
 	b := base.NewBranch(branch)
+	b.Pos = ifs.If
 	branch.Steps = append(branch.Steps, b)
-	errs = ParseFuncBody(ifs.Body, fset, typesInfo, flowDat, b, errs)
+	errs = ParseFuncBody(ifs.Body, fset, typesInfo, comments, flowDat, b, errs)
 	return b, errs
 }
 
@@ -14,19 +14,27 @@ import (
 )
 
 // ParseFuncDecl parses a flow function (or method) declaration.
-func ParseFuncDecl(decl *ast.FuncDecl, fset *token.FileSet, typesInfo *types.Info, flowDat *base.FlowData, errs []error,
+// The comments are used for documenting the data and ports (they may be nil).
+func ParseFuncDecl(
+	decl *ast.FuncDecl, fset *token.FileSet, typesInfo *types.Info, comments ast.CommentMap,
+	flowDat *base.FlowData, errs []error,
 ) []error {
 
+	flowDat.Pos = decl.Name.Pos()
+	flowDat.Doc = docText(decl.Doc)
 	flowDat.ComponentName, flowDat.InPort, errs = ParseFlowFuncName(decl.Name, fset, errs)
 	log.Printf("DEBUG - componentName: %s, inPort: %v", flowDat.ComponentName, flowDat.InPort)
 
-	flowDat.Inputs, errs = parseInputData(decl.Type.Params, fset, typesInfo, errs)
+	flowDat.Inputs, flowDat.Plugins, errs = parseInputData(decl.Type.Params, fset, typesInfo, comments, errs)
 	for _, dat := range flowDat.Inputs {
 		log.Printf("DEBUG - data: %v", dat)
 	}
+	for _, p := range flowDat.Plugins {
+		flowDat.MainBranch.DataMap = base.AddDataToMap(p.Name, p.Typ, flowDat.MainBranch.DataMap)
+	}
 
 	var results []base.DataTyp
-	results, flowDat.OutPorts, errs = parseFlowFuncResults(decl.Type.Results, fset, typesInfo, errs)
+	results, flowDat.OutPorts, errs = parseFlowFuncResults(decl.Type.Results, fset, typesInfo, comments, errs)
 	flowDat.MainBranch.DataMap = base.AddDatasToMap(flowDat.MainBranch.DataMap, flowDat.Inputs)
 	flowDat.MainBranch.DataMap = base.AddDatasToMap(flowDat.MainBranch.DataMap, results)
 	for _, port := range flowDat.OutPorts {
@@ -89,34 +97,83 @@ func ParseFlowFuncName(funcNameID *ast.Ident, fset *token.FileSet, errs []error,
 	return componentName, inPort, errs
 }
 
-func parseInputData(params *ast.FieldList, fset *token.FileSet, typesInfo *types.Info, errs []error,
-) ([]base.DataTyp, []error) {
+// parseInputData parses the parameters of a flow function and splits them
+// into data and plugins.
+func parseInputData(
+	params *ast.FieldList, fset *token.FileSet, typesInfo *types.Info, comments ast.CommentMap, errs []error,
+) ([]base.DataTyp, []base.Plugin, []error) {
 
 	if params == nil || len(params.List) == 0 {
-		return nil, errs
+		return nil, nil, errs
 	}
 
 	var inputs []base.DataTyp
 
-	inputs, errs = flowDataTypes(params, fset, typesInfo, errs)
+	inputs, errs = flowDataTypes(params, fset, typesInfo, comments, errs)
+	goTypes := paramTypes(params, typesInfo)
 
+	datas := make([]base.DataTyp, 0, len(inputs))
+	var plugins []base.Plugin
 	firstPlugin := -1
 	for i, input := range inputs {
-		if firstPlugin < 0 && isPlugin(input) {
-			firstPlugin = i
-		} else if firstPlugin >= 0 && !isPlugin(input) {
+		if base.IsPlugin(input) {
+			if firstPlugin < 0 {
+				firstPlugin = i
+			}
+			plugins = append(plugins, newPlugin(input, goTypes[i]))
+			continue
+		}
+		if firstPlugin >= 0 {
 			errs = append(errs, errors.New(
 				fset.Position(input.NamePos).String()+
 					" flow plugins must all be at the end of the parameter list, found '"+
 					input.Name+"' after plugin '"+inputs[firstPlugin].Name+"'",
 			))
 		}
+		datas = append(datas, input)
 	}
 
-	return inputs, errs
+	return datas, plugins, errs
+}
+
+// paramTypes returns the resolved Go types of the parameters in the order
+// of flowDataTypes. Unknown types are nil.
+func paramTypes(params *ast.FieldList, typesInfo *types.Info) []types.Type {
+	goTypes := make([]types.Type, 0, 32)
+	for _, field := range params.List {
+		var typ types.Type
+		if typesInfo != nil {
+			typ = typesInfo.TypeOf(field.Type)
+		}
+		for n := max(len(field.Names), 1); n > 0; n-- {
+			goTypes = append(goTypes, typ)
+		}
+	}
+	return goTypes
+}
+
+// newPlugin returns the plugin for the data with the method set of its
+// interface type.
+func newPlugin(dat base.DataTyp, typ types.Type) base.Plugin {
+	p := base.Plugin{DataTyp: dat}
+	if typ == nil {
+		return p
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return p
+	}
+	p.Interface = iface
+	qualifier := func(pkg *types.Package) string { return pkg.Name() }
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		p.Methods = append(p.Methods, m.Name()+strings.TrimPrefix(types.TypeString(m.Type(), qualifier), "func"))
+	}
+	return p
 }
 
-func parseFlowFuncResults(funcResults *ast.FieldList, fset *token.FileSet, typesInfo *types.Info, errs []error,
+func parseFlowFuncResults(
+	funcResults *ast.FieldList, fset *token.FileSet, typesInfo *types.Info, comments ast.CommentMap, errs []error,
 ) ([]base.DataTyp, []base.Port, []error) {
 
 	if funcResults == nil || len(funcResults.List) == 0 {
@@ -128,7 +185,7 @@ func parseFlowFuncResults(funcResults *ast.FieldList, fset *token.FileSet, types
 	lastIsError := false
 	ports := []base.Port{}
 
-	datas, _ := flowDataTypes(funcResults, fset, typesInfo, []error{})
+	datas, _ := flowDataTypes(funcResults, fset, typesInfo, comments, []error{})
 	n := len(datas)
 
 	if datas[n-1].Typ == "error" {
@@ -151,7 +208,7 @@ func parseFlowFuncResults(funcResults *ast.FieldList, fset *token.FileSet, types
 			if i == n-1 && lastIsError {
 				break
 			}
-			ports = append(ports, base.Port{Name: portName(dat.Name), Pos: dat.NamePos})
+			ports = append(ports, base.Port{Name: portName(dat.Name), Pos: dat.NamePos, Doc: dat.Doc})
 		}
 	} else if n > 1 || (n == 1 && !lastIsError) {
 		ports = append(ports, defaultPort)
@@ -167,13 +224,14 @@ func parseFlowFuncResults(funcResults *ast.FieldList, fset *token.FileSet, types
 	}
 
 	if lastIsError {
-		ports = append(ports, base.Port{Name: "error", IsError: true})
+		ports = append(ports, base.Port{Name: "error", IsError: true, Doc: datas[n-1].Doc})
 	}
 
 	return datas, ports, errs
 }
 
-func flowDataTypes(fl *ast.FieldList, fset *token.FileSet, typesInfo *types.Info, errs []error,
+func flowDataTypes(
+	fl *ast.FieldList, fset *token.FileSet, typesInfo *types.Info, comments ast.CommentMap, errs []error,
 ) ([]base.DataTyp, []error) {
 
 	datas := make([]base.DataTyp, 0, 32)
@@ -190,24 +248,58 @@ func flowDataTypes(fl *ast.FieldList, fset *token.FileSet, typesInfo *types.Info
 					" "+err.Error()+"; Go data type: "+
 					base.TypeInfo(field.Type, typesInfo))
 		}
+		goTyp := goType(field.Type, typesInfo)
+		doc := fieldDoc(field, comments)
 		for _, id := range field.Names {
 			datas = append(datas, base.DataTyp{
 				Name: id.Name, NamePos: id.NamePos,
-				Typ: flowDataType, TypPos: field.Type.Pos(),
+				Typ: flowDataType, GoTyp: goTyp, TypPos: field.Type.Pos(), Doc: doc,
 			})
 		}
 		if len(field.Names) == 0 {
-			datas = append(datas, base.DataTyp{Typ: flowDataType})
+			datas = append(datas, base.DataTyp{Typ: flowDataType, GoTyp: goTyp, TypPos: field.Type.Pos(), Doc: doc})
 		}
 	}
 
 	return datas, errs
 }
 
-func isPlugin(input base.DataTyp) bool {
-	const prefixPlugin = "plugin"
-	return strings.HasPrefix(input.Name, prefixPlugin) &&
-		(len(input.Name) > len(prefixPlugin))
+// goType returns the resolved Go type of the given type expression or an
+// empty string if the type isn't known.
+func goType(typ ast.Expr, typesInfo *types.Info) string {
+	if typesInfo == nil {
+		return ""
+	}
+	if elli, ok := typ.(*ast.Ellipsis); ok {
+		if ti := typesInfo.TypeOf(elli.Elt); ti != nil {
+			return "[]" + ti.String()
+		}
+		return ""
+	}
+	if ti := typesInfo.TypeOf(typ); ti != nil {
+		return ti.String()
+	}
+	return ""
+}
+
+// docText returns the text of the comment without directives
+// (e.g. '//flowdev:flow') and surrounding white space.
+func docText(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+	return strings.TrimSpace(cg.Text())
+}
+
+// fieldDoc returns the comments above and behind the parameter or result.
+func fieldDoc(field *ast.Field, comments ast.CommentMap) string {
+	docs := make([]string, 0, 2)
+	for _, cg := range comments[field] {
+		if doc := docText(cg); doc != "" {
+			docs = append(docs, doc)
+		}
+	}
+	return strings.Join(docs, "\n")
 }
 
 func portName(longName string) string {
@@ -12,13 +12,16 @@ import (
 	"github.com/flowdev/ea-flow-doc/flow/decl"
 )
 
-func parseAll(allFlowFuncs []find.PackageFuncs) ([]*base.FlowData, []error) {
+// Parse parses all given flow functions and returns the flow data for them.
+// All errors found are returned, too.
+func Parse(allFlowFuncs []find.PackageFuncs) ([]*base.FlowData, []error) {
 	var flowDatas []*base.FlowData
 	var allErrs []error
 
 	for _, pkgFlowFuncs := range allFlowFuncs {
 		for _, flowFunc := range pkgFlowFuncs.Funcs {
-			flowDat, errs := parseFlowFunc(flowFunc, pkgFlowFuncs.Fset, pkgFlowFuncs.TypesInfo)
+			flowDat, errs := parseFlowFunc(flowFunc, pkgFlowFuncs.Fset, pkgFlowFuncs.TypesInfo, pkgFlowFuncs.Comments)
+			flowDat.PkgPath = pkgFlowFuncs.PkgPath
 			flowDatas = append(flowDatas, flowDat)
 			allErrs = append(allErrs, errs...)
 		}
@@ -32,13 +35,14 @@ func parseAll(allFlowFuncs []find.PackageFuncs) ([]*base.FlowData, []error) {
 
 func parseFlowFunc(
 	flowFunc *ast.FuncDecl,
-	fset *token.FileSet, typesInfo *types.Info,
+	fset *token.FileSet, typesInfo *types.Info, comments ast.CommentMap,
 ) (*base.FlowData, []error) {
 	errs := make([]error, 0, 32)
 	flowDat := base.NewFlowData()
+	flowDat.Fset = fset
 
-	errs = decl.ParseFuncDecl(flowFunc, fset, typesInfo, flowDat, errs)
-	errs = body.ParseFuncBody(flowFunc.Body, fset, typesInfo, flowDat, flowDat.MainBranch, errs)
+	errs = decl.ParseFuncDecl(flowFunc, fset, typesInfo, comments, flowDat, errs)
+	errs = body.ParseFuncBody(flowFunc.Body, fset, typesInfo, comments, flowDat, flowDat.MainBranch, errs)
 
 	return flowDat, errs
 }
@@ -1,10 +1,12 @@
 package flow
 
 import (
+	"go/types"
 	"path/filepath"
 	"testing"
 
 	"github.com/flowdev/ea-flow-doc/find"
+	"github.com/flowdev/ea-flow-doc/flow/base"
 	"github.com/flowdev/ea-flow-doc/parse"
 )
 
@@ -17,7 +19,7 @@ func TestParse(t *testing.T) {
 
 	pkgFuncs := find.FlowFuncs(pkgs)
 
-	flowDats, errs := parseAll(pkgFuncs)
+	flowDats, errs := Parse(pkgFuncs)
 	if len(errs) > 0 {
 		t.Fatalf("expected no errors, got: %v", errs)
 	}
@@ -27,6 +29,77 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseDocs(t *testing.T) {
+	root := mustAbs(filepath.Join("testdata", "docs"))
+	pkgs, err := parse.Dir(root, false)
+	if err != nil {
+		t.Fatalf("received unexpected error: %v", err)
+	}
+
+	flowDats, errs := Parse(find.FlowFuncs(pkgs))
+	if len(errs) > 0 {
+		t.Fatalf("expected no errors, got: %v", errs)
+	}
+	if len(flowDats) != 1 {
+		t.Fatalf("expected 1 flow, got: %d", len(flowDats))
+	}
+	fd := flowDats[0]
+
+	expectedDoc := "CancelOrder cancels an order.\n\nShipped orders can't be canceled anymore."
+	if fd.Doc != expectedDoc {
+		t.Errorf("expected flow doc %q, got: %q", expectedDoc, fd.Doc)
+	}
+	if len(fd.Inputs) != 1 || fd.Inputs[0].Doc != "the order to cancel" {
+		t.Errorf("expected input doc %q, got: %v", "the order to cancel", fd.Inputs)
+	}
+	if len(fd.Plugins) != 1 || fd.Plugins[0].Name != "pluginNotifier" {
+		t.Fatalf("expected plugin %q, got: %v", "pluginNotifier", fd.Plugins)
+	}
+	if methods := fd.Plugins[0].Methods; len(methods) != 1 || methods[0] != "Notify(*docs.Order) error" {
+		t.Errorf("expected method %q, got: %q", "Notify(*docs.Order) error", methods)
+	}
+	typs := make([]*types.Package, len(pkgs))
+	for i, pkg := range pkgs {
+		typs[i] = pkg.Types
+	}
+	AddImplementations(flowDats, typs)
+	if impls := fd.Plugins[0].Implementations; len(impls) != 1 || impls[0] != "*github.com/flowdev/ea-flow-doc/flow/testdata/docs.mailer" {
+		t.Errorf("expected implementation %q, got: %q", "*github.com/flowdev/ea-flow-doc/flow/testdata/docs.mailer", impls)
+	}
+	expectedPorts := map[string]string{
+		"canceled": "the canceled order",
+		"shipped":  "the order that has been shipped already",
+		"error":    "",
+	}
+	if len(fd.OutPorts) != len(expectedPorts) {
+		t.Fatalf("expected %d ports, got: %v", len(expectedPorts), fd.OutPorts)
+	}
+	for _, p := range fd.OutPorts {
+		if expected, ok := expectedPorts[p.Name]; !ok || p.Doc != expected {
+			t.Errorf("expected doc %q for port %q, got: %q", expected, p.Name, p.Doc)
+		}
+	}
+
+	steps := fd.MainBranch.Steps
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 steps, got: %v", fd.MainBranch)
+	}
+	call, ok := steps[0].(*base.CallStep)
+	if !ok || call.Group != "checks" || len(call.Notes) != 1 || call.Notes[0] != "by status" {
+		t.Errorf("expected call with group and note, got: %#v", steps[0])
+	}
+	branch, ok := steps[1].(*base.Branch)
+	if !ok || len(branch.Steps) != 3 { // the steps after the return belong to the branch, too
+		t.Fatalf("expected branch with 3 steps, got: %#v", steps[1])
+	}
+	if ret, ok := branch.Steps[0].(*base.ReturnStep); !ok || len(ret.Notes) != 1 || ret.Notes[0] != "too late" {
+		t.Errorf("expected return with note, got: %#v", branch.Steps[0])
+	}
+	if call, ok := branch.Steps[1].(*base.CallStep); !ok || call.Group != "" || len(call.Notes) != 0 {
+		t.Errorf("expected call without group and notes, got: %#v", branch.Steps[1])
+	}
+}
+
 func mustAbs(path string) string {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
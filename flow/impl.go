@@ -0,0 +1,54 @@
+package flow
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/flowdev/ea-flow-doc/flow/base"
+)
+
+// AddImplementations adds the named types of the packages that implement
+// the interfaces of the plugins to all flows.
+// A type implements an interface if the type itself or a pointer to it
+// does. Interfaces without methods and generic types are skipped.
+func AddImplementations(flowDatas []*base.FlowData, pkgs []*types.Package) {
+	named := make([]*types.Named, 0, 256)
+	seen := make(map[string]bool, 256)
+	for _, pkg := range pkgs {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			n, ok := tn.Type().(*types.Named)
+			if !ok || types.IsInterface(n) || n.TypeParams().Len() > 0 || seen[n.String()] {
+				continue
+			}
+			seen[n.String()] = true
+			named = append(named, n)
+		}
+	}
+
+	for _, fd := range flowDatas {
+		for i := range fd.Plugins {
+			p := &fd.Plugins[i]
+			if p.Interface == nil || p.Interface.Empty() {
+				continue
+			}
+			p.Implementations = p.Implementations[:0]
+			for _, n := range named {
+				switch {
+				case types.Implements(n, p.Interface):
+					p.Implementations = append(p.Implementations, n.String())
+				case types.Implements(types.NewPointer(n), p.Interface):
+					p.Implementations = append(p.Implementations, "*"+n.String())
+				}
+			}
+			sort.Slice(p.Implementations, func(i, j int) bool {
+				return strings.TrimPrefix(p.Implementations[i], "*") < strings.TrimPrefix(p.Implementations[j], "*")
+			})
+		}
+	}
+}
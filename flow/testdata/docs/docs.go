@@ -0,0 +1,49 @@
+package docs
+
+// Order is an order of a customer.
+type Order struct {
+	ID string
+}
+
+// Notifier informs customers about their orders.
+type Notifier interface {
+	Notify(*Order) error
+}
+
+// CancelOrder cancels an order.
+//
+// Shipped orders can't be canceled anymore.
+//
+//flowdev:flow
+func CancelOrder(
+	order *Order, // the order to cancel
+	pluginNotifier Notifier,
+) (
+	// the canceled order
+	portCanceled *Order,
+	portShipped *Order, // the order that has been shipped already
+	err error,
+) {
+	//flowdev:group checks
+	//flowdev:note by status
+	shipped := checkShipped(order)
+	if shipped != nil {
+		return nil, shipped, nil //flowdev:note too late
+	}
+	canceled := cancel(order)
+	return canceled, nil, nil
+}
+
+func checkShipped(o *Order) *Order {
+	return nil
+}
+
+func cancel(o *Order) *Order {
+	return o
+}
+
+type mailer struct{}
+
+func (m *mailer) Notify(o *Order) error {
+	return nil
+}
@@ -0,0 +1,3 @@
+module github.com/flowdev/ea-flow-doc/flow/testdata/docs
+
+go 1.24
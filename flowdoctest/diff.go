@@ -0,0 +1,394 @@
+package flowdoctest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diffFiles returns a readable description of the differences between the
+// golden (want) and the generated (got) content of a file.
+func diffFiles(name string, want, got []byte) string {
+	switch filepath.Ext(name) {
+	case ".svg":
+		if diff := diffSVG(want, got); diff != "" {
+			return diff
+		}
+		return diffLines(want, got)
+	case ".md", ".txt", ".json", ".html":
+		return diffLines(want, got)
+	default:
+		return fmt.Sprintf("\tbinary content differs (%d bytes instead of %d)", len(got), len(want))
+	}
+}
+
+// diffLines returns the lines that differ after the common prefix and
+// before the common suffix of both contents.
+func diffLines(want, got []byte) string {
+	wls := strings.Split(string(want), "\n")
+	gls := strings.Split(string(got), "\n")
+	prefix := 0
+	for prefix < len(wls) && prefix < len(gls) && wls[prefix] == gls[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(wls)-prefix && suffix < len(gls)-prefix &&
+		wls[len(wls)-1-suffix] == gls[len(gls)-1-suffix] {
+		suffix++
+	}
+	lines := make([]string, 0, maxDiffLines)
+	lines = append(lines, fmt.Sprintf("line %d:", prefix+1))
+	for _, l := range wls[prefix : len(wls)-suffix] {
+		lines = append(lines, "- "+l)
+	}
+	for _, l := range gls[prefix : len(gls)-suffix] {
+		lines = append(lines, "+ "+l)
+	}
+	return limitLines(lines)
+}
+
+func limitLines(lines []string) string {
+	if len(lines) > maxDiffLines {
+		more := len(lines) - maxDiffLines
+		lines = append(lines[:maxDiffLines], fmt.Sprintf("... and %d more differences", more))
+	}
+	return "\t" + strings.Join(lines, "\n\t")
+}
+
+// --------------------------------------------------------------------------
+// Structural diff of SVG diagrams
+// --------------------------------------------------------------------------
+
+// Kinds of shapes in a diagram.
+const (
+	kindComp   = "comp"
+	kindArrow  = "arrow"
+	kindPath   = "connection"
+	kindText   = "text"
+	kindMarker = "marker"
+)
+
+// svgShape is a component (rectangle or polygon), arrow, routed connection,
+// text or error marker (circle) of a diagram. Arrows and connections start
+// at (x, y) and end at (x2, y2). Polygons and circles are described by
+// their bounding box.
+type svgShape struct {
+	kind   string
+	label  string
+	x, y   float64
+	w, h   float64
+	x2, y2 float64
+	style  string // all attributes except the geometry
+	inComp bool   // texts inside of a component belong to it
+}
+
+type svgDoc struct {
+	viewBox string
+	style   string // style element and background
+	shapes  []*svgShape
+}
+
+// diffSVG returns the differences of the components, arrows and texts of
+// both diagrams or "" if there are none or the diagrams can't be parsed.
+func diffSVG(want, got []byte) string {
+	wd, err := parseSVG(want)
+	if err != nil {
+		return ""
+	}
+	gd, err := parseSVG(got)
+	if err != nil {
+		return ""
+	}
+	lines := make([]string, 0, maxDiffLines)
+	if wd.viewBox != gd.viewBox {
+		lines = append(lines, fmt.Sprintf("diagram size changed from %q to %q", wd.viewBox, gd.viewBox))
+	}
+	if wd.style != gd.style {
+		lines = append(lines, "diagram style changed")
+	}
+	for _, kind := range []string{kindComp, kindArrow, kindPath, kindText, kindMarker} {
+		lines = append(lines, diffShapes(kind, wd.byLabel(kind), gd.byLabel(kind))...)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return limitLines(lines)
+}
+
+func diffShapes(kind string, want, got map[string][]*svgShape) []string {
+	labels := make([]string, 0, len(want)+len(got))
+	for label := range want {
+		labels = append(labels, label)
+	}
+	for label := range got {
+		if _, ok := want[label]; !ok {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+
+	lines := make([]string, 0, 8)
+	for _, label := range labels {
+		ws, gs := want[label], got[label]
+		for i := 0; i < max(len(ws), len(gs)); i++ {
+			switch {
+			case i >= len(gs):
+				lines = append(lines, fmt.Sprintf("removed %s %s at %s", kind, label, ws[i].position()))
+			case i >= len(ws):
+				lines = append(lines, fmt.Sprintf("added %s %s at %s", kind, label, gs[i].position()))
+			default:
+				lines = append(lines, ws[i].diff(gs[i])...)
+			}
+		}
+	}
+	return lines
+}
+
+func (s *svgShape) position() string {
+	switch s.kind {
+	case kindArrow, kindPath:
+		return fmt.Sprintf("(%s,%s)-(%s,%s)", num(s.x), num(s.y), num(s.x2), num(s.y2))
+	default:
+		return fmt.Sprintf("(%s,%s)", num(s.x), num(s.y))
+	}
+}
+
+func (s *svgShape) diff(o *svgShape) []string {
+	lines := make([]string, 0, 3)
+	if s.position() != o.position() {
+		lines = append(lines, fmt.Sprintf("%s %s moved from %s to %s", s.kind, s.label, s.position(), o.position()))
+	}
+	if s.w != o.w || s.h != o.h {
+		lines = append(lines, fmt.Sprintf("%s %s resized from %sx%s to %sx%s",
+			s.kind, s.label, num(s.w), num(s.h), num(o.w), num(o.h)))
+	}
+	if s.style != o.style {
+		lines = append(lines, fmt.Sprintf("%s %s changed style from %q to %q", s.kind, s.label, s.style, o.style))
+	}
+	return lines
+}
+
+// byLabel returns all shapes of the kind by their label.
+// Texts inside of components are left out because they are part of the
+// label of their component.
+func (doc *svgDoc) byLabel(kind string) map[string][]*svgShape {
+	shapes := make(map[string][]*svgShape, len(doc.shapes))
+	for _, s := range doc.shapes {
+		if s.kind == kind && !s.inComp {
+			shapes[s.label] = append(shapes[s.label], s)
+		}
+	}
+	return shapes
+}
+
+func parseSVG(bs []byte) (*svgDoc, error) {
+	doc := &svgDoc{}
+	dec := xml.NewDecoder(bytes.NewReader(bs))
+	var lastLine *svgShape
+	tips := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attrs := attrMap(el.Attr)
+		switch el.Name.Local {
+		case "svg":
+			doc.viewBox = attrs["viewBox"]
+		case "style":
+			var content string
+			if err = dec.DecodeElement(&content, &el); err != nil {
+				return nil, err
+			}
+			doc.style += content
+		case "rect":
+			if attrs["stroke"] == "" { // background
+				doc.style += styleOf(el.Attr)
+				continue
+			}
+			doc.shapes = append(doc.shapes, &svgShape{
+				kind: kindComp,
+				x:    attrNum(attrs, "x"), y: attrNum(attrs, "y"),
+				w: attrNum(attrs, "width"), h: attrNum(attrs, "height"),
+				style: styleOf(el.Attr),
+			})
+		case "polygon":
+			x, y, x2, y2 := boundingBox(strings.Fields(attrs["points"]))
+			doc.shapes = append(doc.shapes, &svgShape{
+				kind: kindComp,
+				x:    x, y: y, w: x2 - x, h: y2 - y,
+				style: styleOf(el.Attr),
+			})
+		case "circle":
+			r := attrNum(attrs, "r")
+			doc.shapes = append(doc.shapes, &svgShape{
+				kind: kindMarker,
+				x:    attrNum(attrs, "cx") - r, y: attrNum(attrs, "cy") - r,
+				w: 2 * r, h: 2 * r,
+				style: styleOf(el.Attr),
+			})
+		case "line":
+			x2, y2 := attrNum(attrs, "x2"), attrNum(attrs, "y2")
+			if lastLine != nil && tips < 2 && lastLine.x2 == x2 && lastLine.y2 == y2 {
+				tips++ // tips belong to their arrow
+				continue
+			}
+			lastLine = &svgShape{
+				kind: kindArrow,
+				x:    attrNum(attrs, "x1"), y: attrNum(attrs, "y1"),
+				x2: x2, y2: y2,
+				style: styleOf(el.Attr),
+			}
+			tips = 0
+			doc.shapes = append(doc.shapes, lastLine)
+		case "polyline":
+			points := strings.Fields(attrs["points"])
+			if len(points) < 2 {
+				continue
+			}
+			x, y := parsePoint(points[0])
+			x2, y2 := parsePoint(points[len(points)-1])
+			lastLine = &svgShape{kind: kindPath, x: x, y: y, x2: x2, y2: y2, style: styleOf(el.Attr)}
+			tips = 0
+			doc.shapes = append(doc.shapes, lastLine)
+		case "text":
+			var content string
+			if err = dec.DecodeElement(&content, &el); err != nil {
+				return nil, err
+			}
+			doc.shapes = append(doc.shapes, &svgShape{
+				kind:  kindText,
+				label: strconv.Quote(content),
+				x:     attrNum(attrs, "x"), y: attrNum(attrs, "y"),
+				w:     attrNum(attrs, "textLength"),
+				style: styleOf(el.Attr),
+			})
+		}
+	}
+	doc.labelShapes()
+	return doc, nil
+}
+
+// labelShapes labels components with their texts and arrows with the
+// shapes they connect.
+func (doc *svgDoc) labelShapes() {
+	for _, c := range doc.shapes {
+		if c.kind != kindComp {
+			continue
+		}
+		texts := make([]string, 0, 4)
+		for _, t := range doc.shapes {
+			if t.kind == kindText && c.contains(t.x, t.y, 0) {
+				texts = append(texts, t.label[1:len(t.label)-1])
+				t.inComp = true
+			}
+		}
+		c.label = strconv.Quote(strings.Join(texts, " "))
+	}
+	for _, a := range doc.shapes {
+		switch a.kind {
+		case kindArrow, kindPath:
+			a.label = "from " + doc.labelAt(a.x, a.y) + " to " + doc.labelAt(a.x2, a.y2)
+		case kindMarker:
+			a.label = "at " + doc.labelAt(a.x+a.w/2, a.y+a.h/2)
+		}
+	}
+}
+
+// labelAt returns the label of the component at the point or of the
+// nearest text.
+func (doc *svgDoc) labelAt(x, y float64) string {
+	const near = 24.0
+	for _, c := range doc.shapes {
+		if c.kind == kindComp && c.contains(x, y, 2) {
+			return c.label
+		}
+	}
+	label, best := "", near
+	for _, t := range doc.shapes {
+		if t.kind != kindText || t.inComp {
+			continue
+		}
+		dx := max(t.x-x, 0, x-(t.x+t.w))
+		dy := max(t.y-near/1.5-y, 0, y-t.y)
+		if d := math.Hypot(dx, dy); d < best {
+			label, best = t.label, d
+		}
+	}
+	if label == "" {
+		return fmt.Sprintf("(%s,%s)", num(x), num(y))
+	}
+	return label
+}
+
+func (s *svgShape) contains(x, y, tolerance float64) bool {
+	return s.x-tolerance <= x && x <= s.x+s.w+tolerance && s.y-tolerance <= y && y <= s.y+s.h+tolerance
+}
+
+func attrMap(attrs []xml.Attr) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		m[a.Name.Local] = a.Value
+	}
+	return m
+}
+
+var geometryAttrs = map[string]bool{
+	"x": true, "y": true, "width": true, "height": true, "textLength": true,
+	"x1": true, "y1": true, "x2": true, "y2": true, "points": true,
+	"cx": true, "cy": true, "r": true,
+}
+
+// styleOf returns all attributes except the geometry in a stable order.
+func styleOf(attrs []xml.Attr) string {
+	parts := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		if !geometryAttrs[a.Name.Local] {
+			parts = append(parts, a.Name.Local+"="+a.Value)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+func attrNum(attrs map[string]string, name string) float64 {
+	f, _ := strconv.ParseFloat(attrs[name], 64)
+	return f
+}
+
+// boundingBox returns the top left and bottom right corner of the points.
+func boundingBox(points []string) (x, y, x2, y2 float64) {
+	for i, p := range points {
+		px, py := parsePoint(p)
+		if i == 0 {
+			x, y, x2, y2 = px, py, px, py
+			continue
+		}
+		x, y, x2, y2 = min(x, px), min(y, py), max(x2, px), max(y2, py)
+	}
+	return x, y, x2, y2
+}
+
+func parsePoint(p string) (float64, float64) {
+	xs, ys, _ := strings.Cut(p, ",")
+	x, _ := strconv.ParseFloat(xs, 64)
+	y, _ := strconv.ParseFloat(ys, 64)
+	return x, y
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,156 @@
+// Package flowdoctest compares the documentation generated for flows with
+// golden files that are checked in with the project.
+//
+// Golden files are kept in a directory with the same layout as the
+// generated documentation. They are created or updated by running the
+// tests with the -flowdoctest.update flag:
+//
+//	go test ./... -flowdoctest.update
+//
+// The flag is namespaced, so it doesn't clash with an -update flag of the
+// tests using this package.
+//
+// Differences in SVG diagrams are reported by component, arrow and text
+// instead of by byte.
+package flowdoctest
+
+import (
+	"bytes"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+	"github.com/flowdev/ea-flow-doc/gen"
+)
+
+var update = flag.Bool("flowdoctest.update", false, "update the golden files of the flow documentation")
+
+// maxDiffLines is the maximum number of differences reported per file.
+const maxDiffLines = 20
+
+// CheckDir generates the documentation for all flows of the Go project in
+// dir and compares it with the golden files in goldenDir.
+func CheckDir(t testing.TB, dir, goldenDir string, opts gen.Options) {
+	t.Helper()
+	flowDatas, err := gen.LoadFlows(dir)
+	if err != nil {
+		t.Fatalf("unable to load flows: %v", err)
+	}
+	files, err := gen.Files(flowDatas, dir, opts)
+	if err != nil {
+		t.Fatalf("unable to generate documentation: %v", err)
+	}
+	CheckFiles(t, files, goldenDir)
+}
+
+// CheckFlow draws the flow and compares the diagrams and the MarkDown file
+// with the golden files in goldenDir.
+func CheckFlow(t testing.TB, flow *draw.Flow, goldenDir string) {
+	t.Helper()
+	svgContents, mdContent, err := flow.Draw()
+	if err != nil {
+		t.Fatalf("unable to draw flow %q: %v", flow.Name(), err)
+	}
+	files := make(map[string][]byte, len(svgContents)+1)
+	for name, content := range svgContents {
+		files[name] = content
+	}
+	files[flow.Name()+".md"] = mdContent
+	CheckFiles(t, files, goldenDir)
+}
+
+// CheckFiles compares the files (by path relative to goldenDir) with the
+// golden files in goldenDir.
+// Golden files without a generated file are reported, too.
+// With the -flowdoctest.update flag the golden files are written instead and
+// superfluous golden files are removed.
+func CheckFiles(t testing.TB, files map[string][]byte, goldenDir string) {
+	t.Helper()
+	golden, err := readGoldenFiles(goldenDir)
+	if err != nil {
+		t.Fatalf("unable to read golden files: %v", err)
+	}
+	if *update {
+		updateGoldenFiles(t, files, golden, goldenDir)
+		return
+	}
+
+	for _, name := range sortedNames(files) {
+		want, ok := golden[name]
+		if !ok {
+			t.Errorf("missing golden file %q (run the tests with -flowdoctest.update to create it)", name)
+			continue
+		}
+		if got := files[name]; !bytes.Equal(got, want) {
+			t.Errorf("file %q differs from its golden file (run the tests with -flowdoctest.update to accept):\n%s",
+				name, diffFiles(name, want, got))
+		}
+	}
+	for _, name := range sortedNames(golden) {
+		if _, ok := files[name]; !ok {
+			t.Errorf("golden file %q isn't generated anymore (run the tests with -flowdoctest.update to remove it)", name)
+		}
+	}
+}
+
+func updateGoldenFiles(t testing.TB, files, golden map[string][]byte, goldenDir string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(goldenDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatalf("unable to create directory for golden file %q: %v", path, err)
+		}
+		if err := os.WriteFile(path, content, 0666); err != nil {
+			t.Fatalf("unable to write golden file %q: %v", path, err)
+		}
+	}
+	for name := range golden {
+		if _, ok := files[name]; !ok {
+			if err := os.Remove(filepath.Join(goldenDir, name)); err != nil {
+				t.Fatalf("unable to remove golden file %q: %v", name, err)
+			}
+		}
+	}
+}
+
+// readGoldenFiles reads all files in the directory tree of goldenDir by
+// their path relative to goldenDir.
+// A missing goldenDir doesn't contain any files.
+func readGoldenFiles(goldenDir string) (map[string][]byte, error) {
+	files := make(map[string][]byte, 64)
+	err := filepath.WalkDir(goldenDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == goldenDir && os.IsNotExist(err) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(goldenDir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = content
+		return nil
+	})
+	return files, err
+}
+
+func sortedNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,135 @@
+package flowdoctest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+	"github.com/flowdev/ea-flow-doc/gen"
+)
+
+func TestCheckDir(t *testing.T) {
+	CheckDir(t, filepath.Join("testdata", "project"), filepath.Join("testdata", "golden"), gen.Options{})
+}
+
+func TestUpdateFlag(t *testing.T) {
+	if flag.Lookup("flowdoctest.update") == nil {
+		t.Errorf("expected flag %q", "flowdoctest.update")
+	}
+	if flag.Lookup("update") != nil {
+		t.Errorf("expected no flag %q that clashes with the flags of other tests", "update")
+	}
+}
+
+// recorder records all errors instead of failing the test.
+type recorder struct {
+	*testing.T
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func buildTestFlow(withData bool) *draw.Flow {
+	flow := draw.NewFlow("goldenTestFlow", draw.FlowModeNoLinks, 1500, nil)
+	arr := draw.NewArrow("", "")
+	if withData {
+		arr.AddDataType("order", "Order", "")
+	}
+	flow.AddStart(
+		draw.NewStartPort("in").AddOutput(
+			arr.AddDestination(
+				draw.NewComp("", "validate", "", flow).AddOutput(
+					draw.NewArrow("", "").AddDestination(draw.NewEndPort("out")),
+				),
+			),
+		),
+	)
+	return flow
+}
+
+func TestCheckFlow(t *testing.T) {
+	goldenDir := t.TempDir()
+	*update = true
+	CheckFlow(t, buildTestFlow(false), goldenDir)
+	*update = false
+
+	specs := []struct {
+		name           string
+		givenFlow      *draw.Flow
+		givenOrphan    bool
+		expectedErrors []string
+	}{
+		{
+			name:      "unchanged",
+			givenFlow: buildTestFlow(false),
+		}, {
+			name:      "moved",
+			givenFlow: buildTestFlow(true),
+			expectedErrors: []string{
+				`file "flowdev/flow-goldenTestFlow.svg" differs`,
+				`comp "validate" moved from (29,1) to (144,1)`,
+				`added text "order" at (`,
+			},
+		}, {
+			name:        "orphan",
+			givenFlow:   buildTestFlow(false),
+			givenOrphan: true,
+			expectedErrors: []string{
+				`golden file "flowdev/flow-oldFlow.svg" isn't generated anymore`,
+			},
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.name, func(t *testing.T) {
+			if spec.givenOrphan {
+				orphan := filepath.Join(goldenDir, "flowdev", "flow-oldFlow.svg")
+				if err := os.WriteFile(orphan, []byte("<svg/>"), 0666); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				defer os.Remove(orphan)
+			}
+			rec := &recorder{T: t}
+			CheckFlow(rec, spec.givenFlow, goldenDir)
+			actual := strings.Join(rec.errors, "\n")
+			for _, expected := range spec.expectedErrors {
+				if !strings.Contains(actual, expected) {
+					t.Errorf("expected errors to contain %q, got:\n%s", expected, actual)
+				}
+			}
+			if len(spec.expectedErrors) == 0 && len(rec.errors) > 0 {
+				t.Errorf("expected no errors, got:\n%s", actual)
+			}
+		})
+	}
+}
+
+func TestDiffSVGShapes(t *testing.T) {
+	svg := func(points string, cx int) []byte {
+		return []byte(`<svg viewBox="0 0 200 100">
+    <polygon class="flowdev-flow" fill="white" stroke="black" points="` + points + `"/>
+    <circle fill="red" cx="` + fmt.Sprint(cx) + `" cy="40" r="6"/>
+    <text fill="black" x="20" y="30" textLength="40">store</text>
+</svg>`)
+	}
+	want := svg("10,10 90,10 90,50 10,50", 90)
+
+	if diff := diffSVG(want, want); diff != "" {
+		t.Errorf("expected no differences, got:\n%s", diff)
+	}
+	diff := diffSVG(want, svg("10,10 120,10 120,50 10,50", 120))
+	for _, expected := range []string{
+		`comp "store" resized from 80x40 to 110x40`,
+		`marker at "store" moved from (84,34) to (114,34)`,
+	} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("expected differences to contain %q, got:\n%s", expected, diff)
+		}
+	}
+}
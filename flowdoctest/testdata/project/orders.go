@@ -0,0 +1,21 @@
+package orders
+
+// Order is an order of a customer.
+type Order struct {
+	ID string
+}
+
+//flowdev:flow
+func ProcessOrder(order *Order) *Order {
+	valid := validate(order)
+	stored := store(valid)
+	return stored
+}
+
+func validate(o *Order) *Order {
+	return o
+}
+
+func store(o *Order) *Order {
+	return o
+}
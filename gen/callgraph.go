@@ -0,0 +1,88 @@
+package gen
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+	"github.com/flowdev/ea-flow-doc/flow/base"
+)
+
+// CallGraphName is the name of the flow showing which flows call which
+// subflows. Its diagrams are put next to the ProjectIndex page.
+const CallGraphName = "callGraph"
+
+// newCallGraph returns a flow with a component for every flow of the call
+// tree and an arrow from every flow to each of its subflows.
+// It starts with the flows that aren't called by other flows.
+// Calls back to a flow that is still being followed become loops, so the
+// graph stays free of cycles.
+// All components link to the MarkDown file of their flow relative to the
+// output root.
+func newCallGraph(ct *callTree, opts Options) *draw.Flow {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	fl := draw.NewFlow(CallGraphName, draw.FlowModeMDLinks, width, opts.Theme).SetFormat(opts.Format)
+	fl.UseLayeredLayout()
+	if opts.FontMetrics {
+		fl.SetTextMetrics(draw.FontMetrics{})
+	}
+
+	comps := make(map[*base.FlowData]*draw.Comp, len(ct.flows))
+	following := make(map[*base.FlowData]bool, len(ct.flows))
+	var follow func(fd *base.FlowData) *draw.Comp
+	follow = func(fd *base.FlowData) *draw.Comp {
+		comp := draw.NewComp("", ct.title(fd), ct.idx.mdFiles[fd], nil).SetKind(draw.KindFlow)
+		comps[fd] = comp
+		following[fd] = true
+		for _, sub := range ct.calls[fd] {
+			arr := draw.NewArrow("", "")
+			switch {
+			case following[sub]:
+				arr.AddDestination(draw.NewLoop(ct.title(sub), "", ct.idx.mdFiles[sub]))
+			case comps[sub] != nil:
+				arr.AddDestination(comps[sub])
+			default:
+				arr.AddDestination(follow(sub))
+			}
+			comp.AddOutput(arr)
+		}
+		following[fd] = false
+		return comp
+	}
+
+	called := ct.called()
+	for _, fd := range ct.flows {
+		if !called[fd] {
+			fl.AddStart(follow(fd))
+		}
+	}
+	for _, fd := range ct.flows { // flows only called in cycles
+		if comps[fd] == nil {
+			fl.AddStart(follow(fd))
+		}
+	}
+	return fl
+}
+
+// addCallGraph draws the call graph into the files and returns the
+// MarkDown showing it in the ProjectIndex page.
+// The diagrams are named like the ones of a flow in the root package.
+func addCallGraph(files map[string][]byte, ct *callTree, opts Options, naming Naming) ([]byte, error) {
+	fl := newCallGraph(ct, opts)
+	fl.SetNaming(naming.Diagrams(&base.FlowData{}, ""))
+	if opts.SharedTiles {
+		fl.UseSharedTiles()
+	}
+	opts.Responsive = false // the call graph is always split into linked tiles
+	svgContents, mdContent, err := drawFlow(fl, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to draw the call graph: %w", err)
+	}
+	for name, content := range svgContents {
+		files[filepath.FromSlash(name)] = content
+	}
+	return mdContent, nil
+}
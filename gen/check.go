@@ -0,0 +1,196 @@
+package gen
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/flowdev/ea-flow-doc/flow/base"
+)
+
+// FileState tells how a generated file differs from the one on disk.
+type FileState int
+
+const (
+	// FileStale is a file whose content on disk differs from the generated one.
+	FileStale FileState = iota
+	// FileMissing is a generated file that doesn't exist on disk.
+	FileMissing
+	// FileOrphaned is a file on disk that looks generated but isn't
+	// generated anymore (e.g. because its flow has been deleted).
+	FileOrphaned
+)
+
+func (s FileState) String() string {
+	switch s {
+	case FileStale:
+		return "stale"
+	case FileMissing:
+		return "missing"
+	default:
+		return "orphaned"
+	}
+}
+
+// FileDiff is a file that isn't up to date on disk.
+// Path is relative to the directory checked.
+type FileDiff struct {
+	Path  string
+	State FileState
+}
+
+// Check compares the generated files with the files in the directory
+// tree of dir and returns all differences sorted by path.
+// Diagrams with a 'flow-' or 'tile-' prefix are orphaned if they aren't
+// generated and they are in a 'flowdev' directory, in a directory with
+// generated diagrams or below the diagram directory of the naming (e.g.
+// DiagramDirNaming.Dir). MarkDown files that aren't generated are
+// orphaned if they show orphaned diagrams. So are the package indexes
+// next to them.
+// The naming has to be the one used to generate the files. Nil means
+// PackageNaming.
+// Hidden directories, 'testdata' and 'vendor' directories are skipped.
+func Check(files map[string][]byte, dir string, naming Naming) ([]FileDiff, error) {
+	diffs := make([]FileDiff, 0, 16)
+	for name, content := range files {
+		onDisk, err := os.ReadFile(filepath.Join(dir, name))
+		switch {
+		case os.IsNotExist(err):
+			diffs = append(diffs, FileDiff{Path: name, State: FileMissing})
+		case err != nil:
+			return nil, err
+		case !bytes.Equal(onDisk, content):
+			diffs = append(diffs, FileDiff{Path: name, State: FileStale})
+		}
+	}
+
+	if naming == nil {
+		naming = PackageNaming{}
+	}
+	orphans, err := findOrphans(files, dir, naming)
+	if err != nil {
+		return nil, err
+	}
+	diffs = append(diffs, orphans...)
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs, nil
+}
+
+func findOrphans(files map[string][]byte, dir string, naming Naming) ([]FileDiff, error) {
+	orphans := make([]FileDiff, 0, 8)
+	orphanDiagrams := make(map[string]bool, 8)
+	mdFiles := make([]string, 0, 8) // MarkDown files that aren't generated
+	diagramDirs := make(map[string]bool, len(files))
+	for name := range files {
+		if isDiagramFile(name) {
+			diagramDirs[filepath.Dir(name)] = true
+		}
+	}
+	// the diagrams of flows without package are in the diagram directory
+	diagramRoot := filepath.Dir(filepath.FromSlash(naming.Diagrams(&base.FlowData{}, "").Diagram("x")))
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if _, ok := files[rel]; ok {
+			return nil
+		}
+		if filepath.Ext(name) == ".md" {
+			mdFiles = append(mdFiles, rel)
+			return nil
+		}
+		if !isDiagramFile(rel) {
+			return nil
+		}
+		relDir := filepath.Dir(rel)
+		if filepath.Base(relDir) != "flowdev" && !diagramDirs[relDir] && !isBelow(relDir, diagramRoot) {
+			return nil
+		}
+		orphans = append(orphans, FileDiff{Path: rel, State: FileOrphaned})
+		orphanDiagrams[rel] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mds := make(map[string]bool, 2*len(mdFiles))
+	for _, md := range mdFiles {
+		shows, err := showsAny(dir, md, orphanDiagrams)
+		if err != nil {
+			return nil, err
+		}
+		if shows { // package indexes of orphaned flows, too
+			mds[md] = true
+			mds[filepath.Join(filepath.Dir(md), PackageIndex)] = true
+		}
+	}
+	for md := range mds {
+		if _, ok := files[md]; ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, md)); err == nil {
+			orphans = append(orphans, FileDiff{Path: md, State: FileOrphaned})
+		}
+	}
+	return orphans, nil
+}
+
+// mdTarget matches the targets of links and images in generated MarkDown
+// files.
+var mdTarget = regexp.MustCompile(`\]\(([^()\s]+)\)|(?:src|srcset)="([^"]+)"`)
+
+// showsAny reports whether the MarkDown file md links to any of the
+// diagrams. All paths are relative to dir.
+func showsAny(dir, md string, diagrams map[string]bool) (bool, error) {
+	if len(diagrams) == 0 {
+		return false, nil
+	}
+	content, err := os.ReadFile(filepath.Join(dir, md))
+	if err != nil {
+		return false, err
+	}
+	for _, m := range mdTarget.FindAllStringSubmatch(string(content), -1) {
+		target := m[1] + m[2]
+		if diagrams[filepath.Join(filepath.Dir(md), filepath.FromSlash(target))] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isBelow reports whether the relative path p is root or inside of it.
+func isBelow(p, root string) bool {
+	if root == "." || root == "" {
+		return false
+	}
+	return p == root || strings.HasPrefix(p, root+string(filepath.Separator))
+}
+
+// isDiagramFile reports whether the file is named like a generated diagram.
+func isDiagramFile(rel string) bool {
+	name := filepath.Base(rel)
+	switch filepath.Ext(name) {
+	case ".svg", ".png", ".pdf":
+	default:
+		return false
+	}
+	return strings.HasPrefix(name, "flow-") || strings.HasPrefix(name, "tile-")
+}
@@ -0,0 +1,341 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+	"github.com/flowdev/ea-flow-doc/flow/base"
+)
+
+// FlowDiff describes how a flow differs between two versions of a project.
+// Old is nil for added flows and New is nil for removed flows.
+type FlowDiff struct {
+	Package string
+	Flow    string
+	Old     *base.FlowData
+	New     *base.FlowData
+	// Changes are the semantic changes of the flow, one per line.
+	// Added parts start with "+ ", removed parts with "- " and changed
+	// parts with "~ ".
+	Changes []string
+
+	oldSteps map[base.Step]draw.Change
+	newSteps map[base.Step]draw.Change
+}
+
+// DiffFlows compares the flows of two versions of a project and returns
+// the flows that differ sorted by package path and flow name.
+// Flows are matched by their package path and name.
+// Steps are matched by the component they call or the port they return
+// to. So moved steps aren't reported.
+func DiffFlows(oldFlows, newFlows []*base.FlowData) []FlowDiff {
+	byName := make(map[string]*FlowDiff, len(newFlows))
+	diffOf := func(fd *base.FlowData) *FlowDiff {
+		key := fd.PkgPath + "." + FlowName(fd)
+		d := byName[key]
+		if d == nil {
+			d = &FlowDiff{Package: fd.PkgPath, Flow: FlowName(fd)}
+			byName[key] = d
+		}
+		return d
+	}
+	for _, fd := range oldFlows {
+		diffOf(fd).Old = fd
+	}
+	for _, fd := range newFlows {
+		diffOf(fd).New = fd
+	}
+
+	diffs := make([]FlowDiff, 0, len(byName))
+	for _, d := range byName {
+		d.compare()
+		if len(d.Changes) > 0 {
+			diffs = append(diffs, *d)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Package != diffs[j].Package {
+			return diffs[i].Package < diffs[j].Package
+		}
+		return diffs[i].Flow < diffs[j].Flow
+	})
+	return diffs
+}
+
+func (d *FlowDiff) compare() {
+	switch {
+	case d.Old == nil:
+		d.Changes = append(d.Changes, "+ flow")
+		d.newSteps = allSteps(d.New, draw.Added)
+		return
+	case d.New == nil:
+		d.Changes = append(d.Changes, "- flow")
+		d.oldSteps = allSteps(d.Old, draw.Removed)
+		return
+	}
+
+	if o, n := inPortName(d.Old), inPortName(d.New); o != n {
+		d.Changes = append(d.Changes, "~ in port: "+o+" -> "+n)
+	}
+	d.diffNames("port", portNames(d.Old.OutPorts), portNames(d.New.OutPorts))
+	d.diffInputs()
+	d.diffData()
+
+	oldSteps, newSteps := steps(d.Old.MainBranch, nil), steps(d.New.MainBranch, nil)
+	d.oldSteps = matchSteps(oldSteps, newSteps, draw.Removed)
+	d.newSteps = matchSteps(newSteps, oldSteps, draw.Added)
+	for _, s := range oldSteps {
+		if d.oldSteps[s] == draw.Removed {
+			d.Changes = append(d.Changes, "- "+stepKey(s))
+		}
+	}
+	for _, s := range newSteps {
+		if d.newSteps[s] == draw.Added {
+			d.Changes = append(d.Changes, "+ "+stepKey(s))
+		}
+	}
+}
+
+// diffNames adds the names that are only in one of the lists.
+func (d *FlowDiff) diffNames(what string, oldNames, newNames []string) {
+	for _, name := range oldNames {
+		if !containsString(newNames, name) {
+			d.Changes = append(d.Changes, "- "+what+" "+name)
+		}
+	}
+	for _, name := range newNames {
+		if !containsString(oldNames, name) {
+			d.Changes = append(d.Changes, "+ "+what+" "+name)
+		}
+	}
+}
+
+// diffInputs adds the inputs that are only in one of the flows or whose
+// type changed.
+func (d *FlowDiff) diffInputs() {
+	oldTypes, newTypes := typeMap(d.Old.Inputs), typeMap(d.New.Inputs)
+	for _, dat := range d.Old.Inputs {
+		if _, ok := newTypes[dat.Name]; !ok {
+			d.Changes = append(d.Changes, "- input "+strings.TrimSpace(dat.Name+" "+dat.Typ))
+		}
+	}
+	for _, dat := range d.New.Inputs {
+		typ, ok := oldTypes[dat.Name]
+		switch {
+		case !ok:
+			d.Changes = append(d.Changes, "+ input "+strings.TrimSpace(dat.Name+" "+dat.Typ))
+		case typ != dat.Typ:
+			d.Changes = append(d.Changes, "~ input "+dat.Name+": "+typ+" -> "+dat.Typ)
+		}
+	}
+}
+
+// diffData adds the data inside of the flows whose type changed.
+// Inputs are compared by diffInputs.
+func (d *FlowDiff) diffData() {
+	inputs := typeMap(d.New.Inputs)
+	oldTypes := typeMap(branchTypes(d.Old.MainBranch))
+	for _, dat := range branchTypes(d.New.MainBranch) {
+		if _, ok := inputs[dat.Name]; ok {
+			continue
+		}
+		if typ, ok := oldTypes[dat.Name]; ok && typ != dat.Typ {
+			d.Changes = append(d.Changes, "~ data "+dat.Name+": "+typ+" -> "+dat.Typ)
+		}
+	}
+}
+
+func typeMap(datas []base.DataTyp) map[string]string {
+	types := make(map[string]string, len(datas))
+	for _, dat := range datas {
+		types[dat.Name] = dat.Typ
+	}
+	return types
+}
+
+func inPortName(fd *base.FlowData) string {
+	if fd.InPort.Name == "" {
+		return "in"
+	}
+	return fd.InPort.Name
+}
+
+func portNames(ports []base.Port) []string {
+	names := make([]string, len(ports))
+	for i, p := range ports {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// branchTypes returns the types of all data of the branch and its
+// sub-branches sorted by name.
+func branchTypes(b *base.Branch) []base.DataTyp {
+	types := make(map[string]string, len(b.DataMap))
+	var collect func(b *base.Branch)
+	collect = func(b *base.Branch) {
+		for name, typ := range b.DataMap {
+			if _, ok := types[name]; !ok && typ != "" {
+				types[name] = typ
+			}
+		}
+		for _, step := range b.Steps {
+			if sub, ok := step.(*base.Branch); ok {
+				collect(sub)
+			}
+		}
+	}
+	collect(b)
+	datas := make([]base.DataTyp, 0, len(types))
+	for name, typ := range types {
+		datas = append(datas, base.DataTyp{Name: name, Typ: typ})
+	}
+	sort.Slice(datas, func(i, j int) bool { return datas[i].Name < datas[j].Name })
+	return datas
+}
+
+// steps returns the calls and returns of the branch and its sub-branches
+// in the order of the source code.
+func steps(b *base.Branch, all []base.Step) []base.Step {
+	for _, step := range b.Steps {
+		if sub, ok := step.(*base.Branch); ok {
+			all = steps(sub, all)
+		} else {
+			all = append(all, step)
+		}
+	}
+	return all
+}
+
+func stepKey(step base.Step) string {
+	switch s := step.(type) {
+	case *base.CallStep:
+		return "call " + s.ComponentName
+	case *base.ReturnStep:
+		return "return " + s.OutPort.Name
+	default:
+		return ""
+	}
+}
+
+// matchSteps returns the steps that aren't in the other steps with the
+// given change.
+func matchSteps(steps, others []base.Step, change draw.Change) map[base.Step]draw.Change {
+	left := make(map[string]int, len(others))
+	for _, s := range others {
+		left[stepKey(s)]++
+	}
+	changes := make(map[base.Step]draw.Change, len(steps))
+	for _, s := range steps {
+		if key := stepKey(s); left[key] > 0 {
+			left[key]--
+		} else {
+			changes[s] = change
+		}
+	}
+	return changes
+}
+
+func allSteps(fd *base.FlowData, change draw.Change) map[base.Step]draw.Change {
+	all := steps(fd.MainBranch, nil)
+	changes := make(map[base.Step]draw.Change, len(all))
+	for _, s := range all {
+		changes[s] = change
+	}
+	return changes
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// --------------------------------------------------------------------------
+// Diff diagrams
+// --------------------------------------------------------------------------
+
+const diffPage = `# Changes of Flow {{.Flow}}
+
+Package ` + "`{{.Package}}`" + `
+
+{{range .Changes}}- ` + "`{{.}}`" + `
+{{end}}
+{{- if .Before}}
+## Before
+
+{{.Before}}
+{{- end}}
+{{- if .After}}
+## After
+
+{{.After}}
+{{- end}}`
+
+var diffTmpl = template.Must(template.New("diffPage").Parse(diffPage))
+
+type diffData struct {
+	Package string
+	Flow    string
+	Changes []string
+	Before  string // MarkDown of the old diagram
+	After   string // MarkDown of the new diagram
+}
+
+// DiffFiles draws the flows of the diffs and returns the content of the
+// generated files by their path relative to the output root.
+// Every flow gets a MarkDown page with its changes and the diagrams before
+// and after the change. Removed components and arrows are drawn in red in
+// the diagram before, added ones in green in the diagram after.
+// The pages are put into directories named by the package paths.
+func DiffFiles(diffs []FlowDiff, opts Options) (map[string][]byte, error) {
+	files := make(map[string][]byte, 4*len(diffs))
+	for _, d := range diffs {
+		dir := d.Package
+		dd := diffData{Package: d.Package, Flow: d.Flow, Changes: d.Changes}
+		for _, version := range []struct {
+			fd      *base.FlowData
+			changes map[base.Step]draw.Change
+			prefix  string
+			md      *string
+		}{
+			{d.Old, d.oldSteps, "flow-old-", &dd.Before},
+			{d.New, d.newSteps, "flow-new-", &dd.After},
+		} {
+			if version.fd == nil {
+				continue
+			}
+			cv := newConverter(version.fd, opts, func(*base.CallStep) string { return "" })
+			cv.changes = version.changes
+			fl, err := cv.convert()
+			if err != nil {
+				return nil, err
+			}
+			fl.SetNaming(draw.PrefixNaming{Dir: "flowdev", Prefix: version.prefix, TileDir: "flowdev"})
+			opts.Responsive = false // the page shows two diagrams
+			svgContents, mdContent, err := drawFlow(fl, opts)
+			if err != nil {
+				return nil, fmt.Errorf("unable to draw flow %q of package %q: %w", d.Flow, d.Package, err)
+			}
+			for name, content := range svgContents {
+				files[filepath.FromSlash(path.Join(dir, name))] = content
+			}
+			*version.md = string(mdContent)
+		}
+		buf := bytes.Buffer{}
+		if err := diffTmpl.Execute(&buf, dd); err != nil {
+			return nil, err
+		}
+		files[filepath.FromSlash(path.Join(dir, d.Flow+".md"))] = buf.Bytes()
+	}
+	return files, nil
+}
@@ -0,0 +1,540 @@
+// Package gen generates the documentation of all flows of a Go project.
+// Every flow is drawn with the draw package into SVG diagrams and a
+// MarkDown file in the directory of its package.
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+	"github.com/flowdev/ea-flow-doc/find"
+	"github.com/flowdev/ea-flow-doc/flow"
+	"github.com/flowdev/ea-flow-doc/flow/base"
+	"github.com/flowdev/ea-flow-doc/parse"
+	xpkgs "github.com/flowdev/ea-flow-doc/x/pkgs"
+)
+
+// DefaultWidth is the maximum width of the diagrams if Options.Width is 0.
+const DefaultWidth = 1500
+
+// Options configure how flows are drawn.
+// The zero value draws every flow into a single light SVG diagram with
+// DefaultWidth.
+type Options struct {
+	Mode   draw.FlowMode
+	Width  int
+	Theme  *draw.Theme
+	Format draw.Format
+	Naming Naming // PackageNaming if nil
+	// SharedTiles names the tiles of FlowModeMDLinks by their content, so
+	// identical tiles of all flows are stored only once.
+	SharedTiles bool
+	// Index adds a PackageIndex page for every package and a ProjectIndex
+	// page with the call graph of all flows to the files.
+	Index bool
+	// BestWidth draws every flow with the narrowest width between MinWidth
+	// and Width that needs the fewest breaks.
+	BestWidth bool
+	// Responsive draws every flow with all widths between MinWidth and
+	// Width that need different breaks. The MarkDown shows the diagram
+	// that fits the screen. It can't be used with FlowModeMDLinks.
+	Responsive bool
+	MinWidth   int // the narrowest width for BestWidth and Responsive
+	// HappyPath hides the error paths of all flows (see
+	// draw.Flow.UseHappyPath).
+	HappyPath bool
+	// FontMetrics measures all texts with the widths of the font of the
+	// theme (see draw.FontMetrics) instead of a fixed width per character.
+	// So boxes fit proportional fonts tightly and texts aren't squeezed.
+	// The flowdoc command uses it by default.
+	FontMetrics bool
+}
+
+// LoadFlows parses all flows in the directory tree starting at dir.
+func LoadFlows(dir string) ([]*base.FlowData, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find directory: %w", err)
+	}
+	pkgs, err := parse.Dir(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	flowDatas, errs := flow.Parse(find.FlowFuncs(pkgs))
+	if len(errs) > 0 {
+		return nil, flowErrors(errs)
+	}
+	typePkgs := make([]*types.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if !xpkgs.IsTestPackage(pkg) && pkg.Types != nil {
+			typePkgs = append(typePkgs, pkg.Types)
+		}
+	}
+	flow.AddImplementations(flowDatas, typePkgs)
+	return flowDatas, nil
+}
+
+func flowErrors(errs []error) error {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = "\t" + err.Error()
+	}
+	return errors.New("found errors in flows:\n" + strings.Join(msgs, "\n"))
+}
+
+// Files draws all flows and returns the content of the generated files by
+// their path relative to the output root.
+// The output root mirrors the package directories of the project in root.
+// Where the files of a flow are put in it is decided by opts.Naming.
+// All links in the MarkDown files are relative, so the output root can be
+// anywhere.
+// Components that are flows themselves link to the MarkDown file of
+// their flow.
+func Files(flowDatas []*base.FlowData, root string, opts Options) (map[string][]byte, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find root directory: %w", err)
+	}
+	naming := opts.Naming
+	if naming == nil {
+		naming = PackageNaming{}
+	}
+	idx := newIndex(flowDatas, root, naming)
+	files := make(map[string][]byte, 4*len(flowDatas))
+	for _, fd := range flowDatas {
+		mdFile := idx.mdFiles[fd]
+		mdDir := path.Dir(mdFile)
+		fl, err := newConverter(fd, opts, func(call *base.CallStep) string {
+			return idx.link(fd, call)
+		}).convert()
+		if err != nil {
+			return nil, err
+		}
+		fl.SetNaming(relNaming{naming: naming.Diagrams(fd, idx.dirs[fd]), dir: mdDir})
+		if opts.SharedTiles {
+			fl.UseSharedTiles()
+		}
+		svgContents, mdContent, err := drawFlow(fl, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to draw flow %q of package %q: %w", fl.Name(), fd.PkgPath, err)
+		}
+		for name, content := range svgContents {
+			files[filepath.FromSlash(path.Join(mdDir, name))] = content
+		}
+		files[filepath.FromSlash(mdFile)] = mdContent
+	}
+	if opts.Index {
+		if err = addIndexes(files, idx, flowDatas, opts, naming); err != nil {
+			return nil, fmt.Errorf("unable to create index pages: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// drawFlow draws the flow with the widths of the options.
+func drawFlow(fl *draw.Flow, opts Options) (map[string][]byte, []byte, error) {
+	if !opts.BestWidth && !opts.Responsive {
+		return fl.Draw()
+	}
+	lopts := fl.LayoutOptions()
+	wr := draw.WidthRange{Min: opts.MinWidth, Max: lopts.Width}
+	if opts.Responsive {
+		layouts, err := fl.Layouts(lopts, wr)
+		if err != nil {
+			return nil, nil, err
+		}
+		return draw.RenderResponsive(layouts, fl.RenderOptions())
+	}
+	layout, err := fl.BestLayout(lopts, wr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return layout.Render(fl.RenderOptions())
+}
+
+// NewFlow converts the flow data to a flow that can be drawn.
+// Components aren't linked.
+func NewFlow(fd *base.FlowData, opts Options) (*draw.Flow, error) {
+	return newConverter(fd, opts, func(*base.CallStep) string { return "" }).convert()
+}
+
+// FlowName returns the name of the flow.
+// It is the name of the component plus the name of the input port if the
+// component has got multiple input ports.
+func FlowName(fd *base.FlowData) string {
+	if fd.InPort.IsImplicit || fd.InPort.Name == "" {
+		return fd.ComponentName
+	}
+	return fd.ComponentName + "_" + fd.InPort.Name
+}
+
+// --------------------------------------------------------------------------
+// Naming of files
+// --------------------------------------------------------------------------
+
+// Naming decides where the files of a flow are put.
+// All paths are slash separated and relative to the output root.
+// pkgDir is the directory of the package of the flow relative to the
+// project root.
+type Naming interface {
+	// MDFile returns the path of the MarkDown file of the flow.
+	MDFile(fd *base.FlowData, pkgDir string) string
+	// Diagrams returns the naming of the diagram files of the flow.
+	Diagrams(fd *base.FlowData, pkgDir string) draw.Naming
+}
+
+// PackageNaming puts the MarkDown file of a flow into the directory of its
+// package and the diagrams into the 'flowdev' directory next to it.
+// Shared tiles are put into the 'flowdev' directory of the output root.
+// It is the default.
+type PackageNaming struct{}
+
+// MDFile implements the Naming interface.
+func (PackageNaming) MDFile(fd *base.FlowData, pkgDir string) string {
+	return path.Join(pkgDir, FlowName(fd)+".md")
+}
+
+// Diagrams implements the Naming interface.
+func (PackageNaming) Diagrams(_ *base.FlowData, pkgDir string) draw.Naming {
+	return draw.PrefixNaming{Dir: path.Join(pkgDir, "flowdev"), Prefix: "flow-", TileDir: "flowdev"}
+}
+
+// DiagramDirNaming puts the MarkDown file of a flow into the directory of
+// its package like PackageNaming.
+// But all diagrams are collected in the directory Dir. They are put into
+// sub-directories by the full package path, so flows with the same name in
+// different packages don't collide.
+// Shared tiles are put into the sub-directory 'tiles' of Dir.
+type DiagramDirNaming struct {
+	Dir string
+}
+
+// MDFile implements the Naming interface.
+func (DiagramDirNaming) MDFile(fd *base.FlowData, pkgDir string) string {
+	return PackageNaming{}.MDFile(fd, pkgDir)
+}
+
+// Diagrams implements the Naming interface.
+func (n DiagramDirNaming) Diagrams(fd *base.FlowData, _ string) draw.Naming {
+	return draw.PrefixNaming{Dir: path.Join(n.Dir, fd.PkgPath), Prefix: "flow-", TileDir: path.Join(n.Dir, "tiles")}
+}
+
+// relNaming makes the names of the diagrams relative to the directory of
+// the MarkDown file.
+type relNaming struct {
+	naming draw.Naming
+	dir    string
+}
+
+func (n relNaming) Diagram(flow string) string {
+	return relPath(n.dir, n.naming.Diagram(flow))
+}
+
+func (n relNaming) Tile(flow string, idx, line int, comp string) string {
+	return relPath(n.dir, n.naming.Tile(flow, idx, line, comp))
+}
+
+func (n relNaming) Filler(flow string, width, height int) string {
+	return relPath(n.dir, n.naming.Filler(flow, width, height))
+}
+
+func (n relNaming) SharedTile(hash string) string {
+	return relPath(n.dir, n.naming.SharedTile(hash))
+}
+
+// relPath returns the slash separated path of target relative to dir.
+func relPath(dir, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(target))
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// --------------------------------------------------------------------------
+// Index of all flows for linking
+// --------------------------------------------------------------------------
+
+type index struct {
+	dirs    map[*base.FlowData]string   // package directories
+	mdFiles map[*base.FlowData]string   // MarkDown files
+	flows   map[string][]*base.FlowData // by package path + "." + flow name
+}
+
+func newIndex(flowDatas []*base.FlowData, root string, naming Naming) *index {
+	idx := &index{
+		dirs:    make(map[*base.FlowData]string, len(flowDatas)),
+		mdFiles: make(map[*base.FlowData]string, len(flowDatas)),
+		flows:   make(map[string][]*base.FlowData, len(flowDatas)),
+	}
+	for _, fd := range flowDatas {
+		idx.dirs[fd] = flowDir(fd, root)
+		idx.mdFiles[fd] = naming.MDFile(fd, idx.dirs[fd])
+		key := fd.PkgPath + "." + fd.ComponentName
+		idx.flows[key] = append(idx.flows[key], fd)
+	}
+	for _, fds := range idx.flows {
+		sort.SliceStable(fds, func(i, j int) bool {
+			return FlowName(fds[i]) < FlowName(fds[j])
+		})
+	}
+	return idx
+}
+
+// link returns the relative link from the MarkDown file of fd to the
+// MarkDown file of the flow called by the step or "" if the called
+// component isn't a flow.
+func (idx *index) link(fd *base.FlowData, call *base.CallStep) string {
+	target := idx.lookup(fd, call)
+	if target == nil {
+		return ""
+	}
+	return relPath(path.Dir(idx.mdFiles[fd]), idx.mdFiles[target])
+}
+
+// lookup returns the flow called by the step in fd or nil if the called
+// component isn't a flow.
+// Calls of other packages are found by the package path resolved by the
+// parser, so packages with the same name don't get mixed up.
+func (idx *index) lookup(fd *base.FlowData, call *base.CallStep) *base.FlowData {
+	comp, pkgPath := call.ComponentName, call.PkgPath
+	if i := strings.LastIndex(comp, "."); i >= 0 {
+		comp = comp[i+1:]
+	} else if pkgPath == "" {
+		pkgPath = fd.PkgPath
+	}
+	if pkgPath == "" {
+		return nil
+	}
+	fds := idx.flows[pkgPath+"."+comp]
+	if len(fds) == 0 {
+		return nil
+	}
+	return fds[0]
+}
+
+// flowDir returns the slash separated directory of the source file of the
+// flow relative to root.
+// Flows without a source file inside of root use their package path.
+func flowDir(fd *base.FlowData, root string) string {
+	if fd.Fset == nil || !fd.Pos.IsValid() {
+		return fd.PkgPath
+	}
+	dir := filepath.Dir(fd.Fset.Position(fd.Pos).Filename)
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fd.PkgPath
+	}
+	return filepath.ToSlash(rel)
+}
+
+// --------------------------------------------------------------------------
+// Conversion of flow data to a flow
+// --------------------------------------------------------------------------
+
+type converter struct {
+	fd      *base.FlowData
+	opts    Options
+	link    func(call *base.CallStep) string
+	plugins map[string]base.Plugin
+	groups  []*draw.Group // in order of appearance
+	byGroup map[string]*draw.Group
+	changes map[base.Step]draw.Change // steps added or removed (see DiffFlows)
+}
+
+func newConverter(fd *base.FlowData, opts Options, link func(call *base.CallStep) string) *converter {
+	cv := &converter{
+		fd:      fd,
+		opts:    opts,
+		link:    link,
+		plugins: make(map[string]base.Plugin, 4),
+		byGroup: make(map[string]*draw.Group, 4),
+	}
+	for _, p := range fd.Plugins {
+		cv.plugins[p.Name] = p
+	}
+	return cv
+}
+
+// openEnd is the place where the next step of a branch is connected.
+// If datas isn't nil, it contains the data types for the next arrow.
+type openEnd struct {
+	attach func(arr *draw.Arrow)
+	datas  []base.DataTyp
+}
+
+func (cv *converter) convert() (*draw.Flow, error) {
+	width := cv.opts.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	fl := draw.NewFlow(FlowName(cv.fd), cv.opts.Mode, width, cv.opts.Theme).SetFormat(cv.opts.Format)
+	fl.SetDescription(cv.fd.Doc)
+	if cv.opts.HappyPath {
+		fl.UseHappyPath()
+	}
+	if cv.opts.FontMetrics {
+		fl.SetTextMetrics(draw.FontMetrics{})
+	}
+	for _, p := range cv.fd.OutPorts {
+		if p.Doc != "" {
+			fl.AddPortDescription(p.Name, strings.Join(strings.Fields(p.Doc), " "))
+		}
+	}
+
+	inPort := cv.fd.InPort.Name
+	if inPort == "" {
+		inPort = "in"
+	}
+	start := draw.NewStartPort(inPort)
+	datas := make([]base.DataTyp, len(cv.fd.Inputs))
+	copy(datas, cv.fd.Inputs)
+	attached := false
+	cv.addSteps(cv.fd.MainBranch, openEnd{
+		attach: func(arr *draw.Arrow) {
+			start.AddOutput(arr)
+			attached = true
+		},
+		datas: datas,
+	}, nil)
+	if !attached {
+		return nil, fmt.Errorf("flow %q of package %q has no steps to draw", fl.Name(), cv.fd.PkgPath)
+	}
+	fl.AddStart(start)
+	for _, g := range cv.groups {
+		fl.AddGroup(g)
+	}
+	return fl, nil
+}
+
+// addSteps adds all steps of the branch to the open end.
+// Sub-branches start at the same open end as the following steps.
+// The parser puts the steps after the return of an if statement into its
+// branch, too. So they continue at the open end of the parent (cont)
+// unless this is the main branch.
+func (cv *converter) addSteps(b *base.Branch, end openEnd, cont *openEnd) {
+	for _, step := range b.Steps {
+		switch s := step.(type) {
+		case *base.CallStep:
+			comp := cv.comp(s).MarkChange(cv.changes[s])
+			datas := end.datas
+			if datas == nil {
+				datas = cv.callDatas(s, b)
+			}
+			arr := addDataTypes(draw.NewArrow("", explicitPort(s.InPort)), datas).MarkChange(cv.changes[s])
+			end.attach(arr.AddDestination(comp))
+			end = openEnd{attach: func(arr *draw.Arrow) { comp.AddOutput(arr) }}
+		case *base.ReturnStep:
+			arr := draw.NewArrow(explicitPort(s.OutPort), "").MarkChange(cv.changes[s])
+			if s.OutPort.IsError {
+				arr.MarkError()
+			}
+			for _, d := range s.Datas {
+				arr.AddDataType(d, "", "")
+			}
+			for _, note := range s.Notes {
+				arr.AddNote(note)
+			}
+			end.attach(arr.AddDestination(draw.NewEndPort(s.OutPort.Name)))
+			if cont == nil {
+				return
+			}
+			end, cont = *cont, nil
+		case *base.Branch:
+			cv.addSteps(s, openEnd{attach: end.attach}, &openEnd{attach: end.attach})
+		}
+	}
+}
+
+func (cv *converter) comp(call *base.CallStep) *draw.Comp {
+	link := cv.link(call)
+	comp := draw.NewComp("", call.ComponentName, link, nil)
+	if link != "" {
+		comp.SetKind(draw.KindFlow)
+	}
+	for _, note := range call.Notes {
+		comp.AddNote(note)
+	}
+	if call.Group != "" {
+		g := cv.byGroup[call.Group]
+		if g == nil {
+			g = draw.NewGroup(call.Group)
+			cv.byGroup[call.Group] = g
+			cv.groups = append(cv.groups, g)
+		}
+		g.AddComp(comp)
+	}
+	for _, in := range call.Inputs {
+		if p, ok := cv.plugins[in]; ok {
+			comp.AddPluginGroup(pluginGroup(p))
+		}
+	}
+	return comp
+}
+
+// pluginGroup returns the plugin group showing the implementations of the
+// plugin found in the project or its type if there are none.
+func pluginGroup(p base.Plugin) *draw.PluginGroup {
+	pg := draw.NewPluginGroup(strings.TrimPrefix(p.Name, "plugin"))
+	if len(p.Implementations) == 0 {
+		return pg.AddPlugin(draw.NewPlugin(p.Typ, ""))
+	}
+	for _, impl := range p.Implementations {
+		pg.AddPlugin(draw.NewPlugin(shortType(impl), ""))
+	}
+	return pg
+}
+
+// shortType returns the Go type with the package name instead of the full
+// package path (e.g. '*orders.DBStore').
+func shortType(goTyp string) string {
+	ptr := ""
+	if strings.HasPrefix(goTyp, "*") {
+		ptr, goTyp = "*", goTyp[1:]
+	}
+	return ptr + path.Base(goTyp)
+}
+
+// callDatas returns the data types of the (non-plugin) inputs of the call.
+func (cv *converter) callDatas(call *base.CallStep, b *base.Branch) []base.DataTyp {
+	datas := make([]base.DataTyp, 0, len(call.Inputs))
+	for _, in := range call.Inputs {
+		if _, ok := cv.plugins[in]; ok || in == "nil" || in == "_" {
+			continue
+		}
+		datas = append(datas, base.DataTyp{Name: in, Typ: typeOf(in, b)})
+	}
+	return datas
+}
+
+// typeOf returns the type of the named data known in the branch or one of
+// its parents.
+func typeOf(name string, b *base.Branch) string {
+	for ; b != nil; b = b.Parent {
+		if typ, ok := b.DataMap[name]; ok && typ != "" {
+			return typ
+		}
+	}
+	return ""
+}
+
+func addDataTypes(arr *draw.Arrow, datas []base.DataTyp) *draw.Arrow {
+	for _, dat := range datas {
+		arr.AddDataType(dat.Name, dat.Typ, "")
+	}
+	return arr
+}
+
+// explicitPort returns the name of the port or "" for implicit ports.
+func explicitPort(p base.Port) string {
+	if p.IsImplicit {
+		return ""
+	}
+	return p.Name
+}
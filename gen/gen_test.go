@@ -0,0 +1,410 @@
+package gen_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/flowdev/ea-flow-doc/draw"
+	"github.com/flowdev/ea-flow-doc/flow/base"
+	"github.com/flowdev/ea-flow-doc/gen"
+)
+
+func TestFiles(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "project"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flowDatas, err := gen.LoadFlows(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files, err := gen.Files(flowDatas, root, gen.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedNames := []string{
+		"orders/ProcessOrder.md",
+		"orders/flowdev/flow-ProcessOrder.svg",
+		"shop/Checkout.md",
+		"shop/flowdev/flow-Checkout.svg",
+	}
+	if actualNames := fileNames(files); strings.Join(actualNames, " ") != strings.Join(expectedNames, " ") {
+		t.Fatalf("expected files %q, got: %q", expectedNames, actualNames)
+	}
+	for name, expectedTexts := range map[string][]string{
+		"orders/flowdev/flow-ProcessOrder.svg": {">validate<", ">store<", ">Store:<", ">order<", ">Order)<",
+			`font-style="italic" x="`, ">saves with retries<", `stroke-dasharray="6 3"`, ">persistence<", ">*storage.DB<"},
+		"shop/flowdev/flow-Checkout.svg": {">newOrder<", ">orders.ProcessOrder<", ">cart<", `<polygon class="flowdev-flow"`},
+	} {
+		for _, text := range expectedTexts {
+			if !strings.Contains(string(files[name]), text) {
+				t.Errorf("expected %q to contain %q, got:\n%s", name, text, files[name])
+			}
+		}
+	}
+
+	files, err = gen.Files(flowDatas, root, gen.Options{Mode: draw.FlowModeMDLinks})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if md := string(files["shop/Checkout.md"]); !strings.Contains(md, "](../orders/ProcessOrder.md)") {
+		t.Errorf("expected link to the subflow, got:\n%s", md)
+	}
+
+	files, err = gen.Files(flowDatas, root, gen.Options{Naming: gen.DiagramDirNaming{Dir: "docs/diagrams"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedNames = []string{
+		"docs/diagrams/example.com/project/orders/flow-ProcessOrder.svg",
+		"docs/diagrams/example.com/project/shop/flow-Checkout.svg",
+		"orders/ProcessOrder.md",
+		"shop/Checkout.md",
+	}
+	if actualNames := fileNames(files); strings.Join(actualNames, " ") != strings.Join(expectedNames, " ") {
+		t.Fatalf("expected files %q, got: %q", expectedNames, actualNames)
+	}
+	expectedLink := "(../docs/diagrams/example.com/project/orders/flow-ProcessOrder.svg)"
+	if md := string(files["orders/ProcessOrder.md"]); !strings.Contains(md, expectedLink) {
+		t.Errorf("expected link %q to the diagram, got:\n%s", expectedLink, md)
+	}
+
+	files, err = gen.Files(flowDatas, root, gen.Options{Mode: draw.FlowModeMDLinks, SharedTiles: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiles := 0
+	for _, name := range fileNames(files) {
+		if strings.HasSuffix(name, ".md") {
+			continue
+		}
+		tiles++
+		if !strings.HasPrefix(name, "flowdev/tile-") {
+			t.Errorf("expected shared tile in the root directory, got: %q", name)
+		}
+		link := "(../" + name + ")"
+		if !strings.Contains(string(files["orders/ProcessOrder.md"]), link) &&
+			!strings.Contains(string(files["shop/Checkout.md"]), link) {
+			t.Errorf("expected a link %q to the shared tile", link)
+		}
+	}
+	if tiles == 0 {
+		t.Errorf("expected shared tiles, got files: %q", fileNames(files))
+	}
+}
+
+func TestFilesSameName(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "samename"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flowDatas, err := gen.LoadFlows(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, err := gen.Files(flowDatas, root, gen.Options{Mode: draw.FlowModeMDLinks})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, expectedLinks := range map[string][]string{
+		"z/util/Main.md": {"](Step.md)"},
+		"app/Run.md":     {"](../b/util/Step.md)", "](../z/util/Main.md)"},
+	} {
+		md := string(files[name])
+		for _, link := range expectedLinks {
+			if !strings.Contains(md, link) {
+				t.Errorf("expected %q to contain link %q, got:\n%s", name, link, md)
+			}
+		}
+	}
+	if md := string(files["z/util/Main.md"]); strings.Contains(md, "b/util") {
+		t.Errorf("expected no link to the other util package, got:\n%s", md)
+	}
+
+	files, err = gen.Files(flowDatas, root, gen.Options{Index: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedHierarchy := "- [app.Run](app/Run.md)\n" +
+		"  - [b/util.Step](b/util/Step.md)\n" +
+		"  - [z/util.Main](z/util/Main.md)\n" +
+		"    - [z/util.Step](z/util/Step.md)\n"
+	if md := string(files["index.md"]); !strings.Contains(md, expectedHierarchy) {
+		t.Errorf("expected call hierarchy %q, got:\n%s", expectedHierarchy, md)
+	}
+	for _, expected := range []string{
+		"[![b/util.Step](flowdev/flow-callGraph-2-0-b_util.Step.svg)](b/util/Step.md)",
+		"[![z/util.Step](flowdev/flow-callGraph-4-1-z_util.Step.svg)](z/util/Step.md)",
+	} {
+		if md := string(files["index.md"]); !strings.Contains(md, expected) {
+			t.Errorf("expected call graph to contain %q, got:\n%s", expected, md)
+		}
+	}
+	if svg := string(files["flowdev/flow-callGraph-2-0-b_util.Step.svg"]); !strings.Contains(svg, ">b/util.Step<") {
+		t.Errorf("expected call graph tile of %q, got:\n%s", "b/util.Step", svg)
+	}
+}
+
+func TestFilesIndex(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "project"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flowDatas, err := gen.LoadFlows(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, err := gen.Files(flowDatas, root, gen.Options{Index: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, expectedTexts := range map[string][]string{
+		"index.md": {
+			"- example.com/project\n  - [orders](orders/flows.md)\n  - [shop](shop/flows.md)\n",
+			"- [shop.Checkout](shop/Checkout.md)\n  - [orders.ProcessOrder](orders/ProcessOrder.md)\n",
+			"## Call Graph\n\n[![shop.Checkout](flowdev/flow-callGraph-0-0-shop.Checkout.svg)](shop/Checkout.md)",
+			"[![orders.ProcessOrder](flowdev/flow-callGraph-2-0-orders.ProcessOrder.svg)](orders/ProcessOrder.md)",
+		},
+		"flowdev/flow-callGraph-0-0-shop.Checkout.svg": {">shop.Checkout<"},
+		"orders/flows.md": {
+			"Package `example.com/project/orders`",
+			"| [ProcessOrder](ProcessOrder.md) | ProcessOrder validates and stores an order. | in: order Order | pluginStore Store (*storage.DB) | out |",
+			"[All flows](../index.md)",
+		},
+		"orders/ProcessOrder.md": {
+			"ProcessOrder validates and stores an order.\nInvalid orders aren't stored.\n\n![ProcessOrder]",
+			"[Flows of package orders](flows.md)",
+		},
+		"shop/flows.md": {"| [Checkout](Checkout.md) |  | in: cart Cart |  | out |"},
+	} {
+		for _, text := range expectedTexts {
+			if !strings.Contains(string(files[name]), text) {
+				t.Errorf("expected %q to contain %q, got:\n%s", name, text, files[name])
+			}
+		}
+	}
+}
+
+func TestFilesIndexEscaping(t *testing.T) {
+	fd := base.NewFlowData()
+	fd.PkgPath, fd.ComponentName = "example.com/project/pick", "Pick"
+	fd.Doc = "Pick picks a or b."
+	fd.Inputs = []base.DataTyp{{Name: "choice", Typ: "Either[a|b]"}}
+	fd.Plugins = []base.Plugin{{DataTyp: base.DataTyp{Name: "pluginRule", Typ: "Rule[a|b]"}}}
+	fd.OutPorts = []base.Port{{Name: "out"}}
+	fd.MainBranch.Steps = []base.Step{
+		&base.CallStep{ComponentName: "pick"},
+		&base.ReturnStep{OutPort: base.Port{Name: "out"}},
+	}
+	files, err := gen.Files([]*base.FlowData{fd}, ".", gen.Options{Index: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "| [Pick](Pick.md) | Pick picks a or b. | in: choice Either[a\\|b] | pluginRule Rule[a\\|b] | out |"
+	md := string(files[filepath.Join("example.com", "project", "pick", "flows.md")])
+	if !strings.Contains(md, expected) {
+		t.Errorf("expected escaped table row %q, got:\n%s", expected, md)
+	}
+}
+
+func TestFilesResponsive(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "project"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flowDatas, err := gen.LoadFlows(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, err := gen.Files(flowDatas, root, gen.Options{Width: 800, MinWidth: 100, Responsive: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	md := string(files["orders/ProcessOrder.md"])
+	if !strings.Contains(md, "<picture><source ") || !strings.Contains(md, `src="flowdev/flow-ProcessOrder.svg">`) {
+		t.Errorf("expected a picture with many widths, got:\n%s", md)
+	}
+
+	_, err = gen.Files(flowDatas, root, gen.Options{Mode: draw.FlowModeMDLinks, Responsive: true})
+	if err == nil || !strings.Contains(err.Error(), "many diagrams") {
+		t.Errorf("expected error for split diagrams, got: %v", err)
+	}
+}
+
+func TestFilesHappyPath(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "errors"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flowDatas, err := gen.LoadFlows(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const name = "payments/flowdev/flow-Pay.svg"
+	files, err := gen.Files(flowDatas, root, gen.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svg := string(files[name]); !strings.Contains(svg, `stroke-dasharray`) || !strings.Contains(svg, ">error<") {
+		t.Errorf("expected an error path, got:\n%s", svg)
+	}
+
+	files, err = gen.Files(flowDatas, root, gen.Options{HappyPath: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svg := string(files[name]); strings.Contains(svg, ">error<") || !strings.Contains(svg, "<circle") {
+		t.Errorf("expected the happy path with an error marker, got:\n%s", svg)
+	}
+}
+
+func TestFlowStats(t *testing.T) {
+	flowDatas, err := gen.LoadFlows(filepath.Join("testdata", "project"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stats := gen.FlowStats(flowDatas)
+	expected := []gen.Stats{
+		{Package: "example.com/project/orders", Flow: "ProcessOrder",
+			Steps: 4, Depth: 1, Ports: 1, Plugins: 1, FanIn: 1, FanOut: 0, LongestPath: 2},
+		{Package: "example.com/project/shop", Flow: "Checkout",
+			Steps: 3, Depth: 0, Ports: 1, Plugins: 0, FanIn: 0, FanOut: 1, LongestPath: 2},
+	}
+	if fmt.Sprint(stats) != fmt.Sprint(expected) {
+		t.Errorf("expected stats:\n%v\ngot:\n%v", expected, stats)
+	}
+
+	violations := gen.Limits{Steps: 3, FanOut: 1}.Check(stats)
+	if len(violations) != 1 || violations[0].Error() !=
+		`flow "ProcessOrder" of package "example.com/project/orders": steps is 4 (limit: 3)` {
+		t.Errorf("expected a single violation of the steps, got: %v", violations)
+	}
+
+	flowDatas, err = gen.LoadFlows(filepath.Join("testdata", "samename"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fans := make([]string, 0, 4)
+	for _, s := range gen.FlowStats(flowDatas) {
+		fans = append(fans, fmt.Sprintf("%s.%s %d/%d", s.Package, s.Flow, s.FanIn, s.FanOut))
+	}
+	expectedFans := []string{
+		"example.com/samename/app.Run 0/2",
+		"example.com/samename/b/util.Step 1/0",
+		"example.com/samename/z/util.Main 1/1",
+		"example.com/samename/z/util.Step 1/0",
+	}
+	if strings.Join(fans, "; ") != strings.Join(expectedFans, "; ") {
+		t.Errorf("expected fan-in/fan-out:\n%q\ngot:\n%q", expectedFans, fans)
+	}
+}
+
+func TestDiffFlows(t *testing.T) {
+	flowDatas, err := gen.LoadFlows(filepath.Join("testdata", "project"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diffs := gen.DiffFlows(flowDatas, flowDatas); len(diffs) != 0 {
+		t.Errorf("expected no diffs, got: %v", diffs)
+	}
+
+	newFlow := func(calls ...string) *base.FlowData {
+		fd := base.NewFlowData()
+		fd.PkgPath, fd.ComponentName = "example.com/project/orders", "ProcessOrder"
+		fd.OutPorts = []base.Port{{Name: "out"}}
+		for _, call := range calls {
+			fd.MainBranch.Steps = append(fd.MainBranch.Steps, &base.CallStep{ComponentName: call})
+		}
+		fd.MainBranch.Steps = append(fd.MainBranch.Steps, &base.ReturnStep{OutPort: base.Port{Name: "out"}})
+		return fd
+	}
+	diffs := gen.DiffFlows(
+		[]*base.FlowData{newFlow("validate", "store")},
+		[]*base.FlowData{newFlow("validate", "check", "store")},
+	)
+	if len(diffs) != 1 || strings.Join(diffs[0].Changes, "; ") != "+ call check" {
+		t.Fatalf("expected an added call, got: %v", diffs)
+	}
+
+	files, err := gen.DiffFiles(diffs, gen.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	md := string(files[filepath.Join("example.com", "project", "orders", "ProcessOrder.md")])
+	for _, expected := range []string{"- `+ call check`", "## Before\n\n![ProcessOrder](flowdev/flow-old-ProcessOrder.svg)", "## After"} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("expected page to contain %q, got:\n%s", expected, md)
+		}
+	}
+	svg := string(files[filepath.Join("example.com", "project", "orders", "flowdev", "flow-new-ProcessOrder.svg")])
+	if n := strings.Count(svg, `class="flowdev-added"`); n != 1 {
+		t.Errorf("expected 1 added component, got %d in:\n%s", n, svg)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	files := map[string][]byte{
+		filepath.Join("orders", "ProcessOrder.md"):                  []byte("md"),
+		filepath.Join("orders", "flowdev", "flow-ProcessOrder.svg"): []byte("svg"),
+		filepath.Join("shop", "Checkout.md"):                        []byte("md"),
+		filepath.Join("shop", "flowdev", "flow-Checkout.svg"):       []byte("svg"),
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"README.md":                            "# Project",
+		"flowdev/tile-0123456789abcdef.svg":    "svg",
+		"orders/ProcessOrder.md":               "md",
+		"orders/flowdev/flow-ProcessOrder.svg": "old svg",
+		"orders/Notes.md":                      "notes",
+		"orders/CancelOrder.md":                "![CancelOrder](flowdev/flow-CancelOrder.png)\n",
+		"orders/flows.md":                      "index",
+		"orders/flowdev/flow-CancelOrder.png":  "png",
+		"orders/flowdev/logo.svg":              "svg",
+		"shop/Checkout.md":                     "md",
+		"testdata/flowdev/flow-Test.svg":       "svg",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	diffs, err := gen.Check(files, dir, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"orphaned flowdev/tile-0123456789abcdef.svg",
+		"orphaned orders/CancelOrder.md",
+		"orphaned orders/flowdev/flow-CancelOrder.png",
+		"stale orders/flowdev/flow-ProcessOrder.svg",
+		"orphaned orders/flows.md",
+		"missing shop/flowdev/flow-Checkout.svg",
+	}
+	actual := make([]string, len(diffs))
+	for i, d := range diffs {
+		actual[i] = fmt.Sprintf("%s %s", d.State, filepath.ToSlash(d.Path))
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diffs:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func fileNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, filepath.ToSlash(name))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,402 @@
+package gen
+
+import (
+	"bytes"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/flowdev/ea-flow-doc/flow/base"
+)
+
+// Names of the generated index pages.
+const (
+	// PackageIndex is the name of the page listing the flows of a package.
+	// It is put next to the MarkDown files of the flows.
+	PackageIndex = "flows.md"
+	// ProjectIndex is the name of the page in the output root with the
+	// package tree, the call hierarchy and the call graph of all flows.
+	ProjectIndex = "index.md"
+)
+
+const pkgIndexPage = `# Flows of Package {{.Name}}
+
+Package ` + "`{{.Path}}`" + `
+
+| Flow | Description | Input | Plugins | Outputs |
+| --- | --- | --- | --- | --- |
+{{range .Flows -}}
+| [{{cell .Name}}]({{.Link}}) | {{cell .Description}} | {{cell .Input}} | {{cell .Plugins}} | {{cell .Outputs}} |
+{{end}}
+[All flows]({{.ProjectLink}})
+`
+
+const projectIndexPage = `# Flows
+
+## Packages
+
+{{range .Packages}}{{.}}
+{{end}}
+## Call Hierarchy
+
+{{range .Calls}}{{.}}
+{{end}}
+## Call Graph
+
+{{.CallGraph}}`
+
+var (
+	pkgIndexTmpl     = template.Must(template.New("pkgIndex").Funcs(template.FuncMap{"cell": tableCell}).Parse(pkgIndexPage))
+	projectIndexTmpl = template.Must(template.New("projectIndex").Parse(projectIndexPage))
+)
+
+type pkgIndex struct {
+	Name        string
+	Path        string
+	Flows       []flowEntry
+	ProjectLink string
+}
+
+type flowEntry struct {
+	Name        string
+	Link        string
+	Description string
+	Input       string
+	Plugins     string
+	Outputs     string
+}
+
+type projectIndex struct {
+	Packages  []string // lines of the package tree
+	Calls     []string // lines of the call hierarchy
+	CallGraph string   // MarkDown of the call graph diagram
+}
+
+// addIndexes adds the index pages of all packages and the project to the
+// files and links the MarkDown files of the flows to their package index.
+// The project index shows the call graph of all flows, too.
+func addIndexes(files map[string][]byte, idx *index, flowDatas []*base.FlowData, opts Options, naming Naming) error {
+	pkgs := make(map[string][]*base.FlowData, len(flowDatas))
+	for _, fd := range flowDatas {
+		pkgs[fd.PkgPath] = append(pkgs[fd.PkgPath], fd)
+	}
+	tree := &pkgNode{}
+	for pkgPath, fds := range pkgs {
+		sort.Slice(fds, func(i, j int) bool {
+			return FlowName(fds[i]) < FlowName(fds[j])
+		})
+		page := path.Join(path.Dir(idx.mdFiles[fds[0]]), PackageIndex)
+		content, err := pkgIndexToBytes(pkgPath, fds, page, idx)
+		if err != nil {
+			return err
+		}
+		files[filepath.FromSlash(page)] = content
+		for _, fd := range fds {
+			md := filepath.FromSlash(idx.mdFiles[fd])
+			files[md] = append(files[md], "[Flows of package "+path.Base(pkgPath)+"]("+
+				relPath(path.Dir(idx.mdFiles[fd]), page)+")\n"...)
+		}
+		tree.insert(strings.Split(pkgPath, "/"), page)
+	}
+
+	ct := newCallTree(idx, flowDatas)
+	graph, err := addCallGraph(files, ct, opts, naming)
+	if err != nil {
+		return err
+	}
+	pi := projectIndex{
+		Packages:  tree.lines(""),
+		Calls:     ct.lines(),
+		CallGraph: string(graph),
+	}
+	buf := bytes.Buffer{}
+	if err := projectIndexTmpl.Execute(&buf, pi); err != nil {
+		return err
+	}
+	files[ProjectIndex] = buf.Bytes()
+	return nil
+}
+
+func pkgIndexToBytes(pkgPath string, fds []*base.FlowData, page string, idx *index) ([]byte, error) {
+	dir := path.Dir(page)
+	pi := pkgIndex{
+		Name:        path.Base(pkgPath),
+		Path:        pkgPath,
+		Flows:       make([]flowEntry, len(fds)),
+		ProjectLink: relPath(dir, ProjectIndex),
+	}
+	for i, fd := range fds {
+		pi.Flows[i] = flowEntry{
+			Name:        FlowName(fd),
+			Link:        relPath(dir, idx.mdFiles[fd]),
+			Description: synopsis(fd.Doc),
+			Input:       inputString(fd),
+			Plugins:     pluginsString(fd.Plugins),
+			Outputs:     portsString(fd.OutPorts),
+		}
+	}
+	buf := bytes.Buffer{}
+	if err := pkgIndexTmpl.Execute(&buf, pi); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// synopsis returns the first sentence of the first paragraph of the doc
+// comment on a single line.
+func synopsis(doc string) string {
+	para, _, _ := strings.Cut(doc, "\n\n")
+	text := strings.Join(strings.Fields(para), " ")
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	return text
+}
+
+// tableCell escapes the text for a cell of a MarkDown table.
+// Go types can contain '|' (e.g. in constraints) that would end the cell.
+func tableCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+func inputString(fd *base.FlowData) string {
+	name := fd.InPort.Name
+	if name == "" {
+		name = "in"
+	}
+	if datas := dataString(fd.Inputs); datas != "" {
+		return name + ": " + datas
+	}
+	return name
+}
+
+// dataString returns the data as comma separated list.
+func dataString(datas []base.DataTyp) string {
+	strs := make([]string, 0, len(datas))
+	for _, dat := range datas {
+		strs = append(strs, strings.TrimSpace(dat.Name+" "+dat.Typ))
+	}
+	return strings.Join(strs, ", ")
+}
+
+// pluginsString returns the plugins with their implementations as comma
+// separated list.
+func pluginsString(plugins []base.Plugin) string {
+	strs := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		str := strings.TrimSpace(p.Name + " " + p.Typ)
+		if len(p.Implementations) > 0 {
+			impls := make([]string, len(p.Implementations))
+			for i, impl := range p.Implementations {
+				impls[i] = shortType(impl)
+			}
+			str += " (" + strings.Join(impls, ", ") + ")"
+		}
+		strs = append(strs, str)
+	}
+	return strings.Join(strs, ", ")
+}
+
+func portsString(ports []base.Port) string {
+	names := make([]string, len(ports))
+	for i, p := range ports {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// --------------------------------------------------------------------------
+// Package tree
+// --------------------------------------------------------------------------
+
+// pkgNode is a part of a package path.
+// Nodes of packages with flows have got a page.
+type pkgNode struct {
+	page     string
+	children map[string]*pkgNode
+}
+
+func (n *pkgNode) insert(parts []string, page string) {
+	if len(parts) == 0 {
+		n.page = page
+		return
+	}
+	if n.children == nil {
+		n.children = make(map[string]*pkgNode, 8)
+	}
+	child := n.children[parts[0]]
+	if child == nil {
+		child = &pkgNode{}
+		n.children[parts[0]] = child
+	}
+	child.insert(parts[1:], page)
+}
+
+// lines returns the lines of the children of the node as nested list.
+// Parts of package paths without flows are combined with their only child.
+func (n *pkgNode) lines(indent string) []string {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		child := n.children[name]
+		for child.page == "" && len(child.children) == 1 {
+			for grandName, grandChild := range child.children {
+				name, child = name+"/"+grandName, grandChild
+			}
+		}
+		if child.page != "" {
+			lines = append(lines, indent+"- ["+name+"]("+child.page+")")
+		} else {
+			lines = append(lines, indent+"- "+name)
+		}
+		lines = append(lines, child.lines(indent+"  ")...)
+	}
+	return lines
+}
+
+// --------------------------------------------------------------------------
+// Call hierarchy
+// --------------------------------------------------------------------------
+
+type callTree struct {
+	idx      *index
+	flows    []*base.FlowData
+	calls    map[*base.FlowData][]*base.FlowData
+	shown    map[*base.FlowData]bool
+	pkgNames map[string]string // short unique package names by path
+}
+
+func newCallTree(idx *index, flowDatas []*base.FlowData) *callTree {
+	ct := &callTree{
+		idx:      idx,
+		flows:    make([]*base.FlowData, len(flowDatas)),
+		calls:    make(map[*base.FlowData][]*base.FlowData, len(flowDatas)),
+		shown:    make(map[*base.FlowData]bool, len(flowDatas)),
+		pkgNames: shortPkgNames(flowDatas),
+	}
+	copy(ct.flows, flowDatas)
+	sort.Slice(ct.flows, func(i, j int) bool {
+		return ct.title(ct.flows[i]) < ct.title(ct.flows[j])
+	})
+	for _, fd := range ct.flows {
+		ct.calls[fd] = ct.subflows(fd, fd.MainBranch, ct.calls[fd])
+	}
+	return ct
+}
+
+// subflows adds all flows called in the branch to calls.
+func (ct *callTree) subflows(fd *base.FlowData, b *base.Branch, calls []*base.FlowData) []*base.FlowData {
+	for _, step := range b.Steps {
+		switch s := step.(type) {
+		case *base.CallStep:
+			if sub := ct.idx.lookup(fd, s); sub != nil && !contains(calls, sub) {
+				calls = append(calls, sub)
+			}
+		case *base.Branch:
+			calls = ct.subflows(fd, s, calls)
+		}
+	}
+	return calls
+}
+
+// lines returns the call hierarchy as nested list.
+// It starts with the flows that aren't called by other flows.
+// Every flow is expanded only once.
+func (ct *callTree) lines() []string {
+	called := ct.called()
+	lines := make([]string, 0, 2*len(ct.flows))
+	for _, fd := range ct.flows {
+		if !called[fd] {
+			lines = ct.addLines(lines, fd, "")
+		}
+	}
+	for _, fd := range ct.flows { // flows only called in cycles
+		if !ct.shown[fd] {
+			lines = ct.addLines(lines, fd, "")
+		}
+	}
+	return lines
+}
+
+// called returns the flows that are called by other flows.
+func (ct *callTree) called() map[*base.FlowData]bool {
+	called := make(map[*base.FlowData]bool, len(ct.flows))
+	for fd, calls := range ct.calls {
+		for _, sub := range calls {
+			if sub != fd {
+				called[sub] = true
+			}
+		}
+	}
+	return called
+}
+
+func (ct *callTree) addLines(lines []string, fd *base.FlowData, indent string) []string {
+	line := indent + "- [" + ct.title(fd) + "](" + ct.idx.mdFiles[fd] + ")"
+	if ct.shown[fd] {
+		return append(lines, line+" (see above)")
+	}
+	ct.shown[fd] = true
+	lines = append(lines, line)
+	for _, sub := range ct.calls[fd] {
+		lines = ct.addLines(lines, sub, indent+"  ")
+	}
+	return lines
+}
+
+// title returns the name of the flow qualified by its short package name.
+func (ct *callTree) title(fd *base.FlowData) string {
+	return ct.pkgNames[fd.PkgPath] + "." + FlowName(fd)
+}
+
+// shortPkgNames returns the shortest suffixes of the package paths of the
+// flows that are unique among them.
+// So packages with the same name are told apart (e.g. "b/util" and
+// "z/util") and all others keep their name.
+func shortPkgNames(flowDatas []*base.FlowData) map[string]string {
+	paths := make([]string, 0, len(flowDatas))
+	for _, fd := range flowDatas {
+		if !containsString(paths, fd.PkgPath) {
+			paths = append(paths, fd.PkgPath)
+		}
+	}
+	names := make(map[string]string, len(paths))
+	for _, p := range paths {
+		elems := strings.Split(p, "/")
+		for n := 1; n <= len(elems); n++ {
+			name := strings.Join(elems[len(elems)-n:], "/")
+			if uniqueSuffix(paths, p, name) {
+				names[p] = name
+				break
+			}
+		}
+	}
+	return names
+}
+
+// uniqueSuffix returns true if no other path than p ends with the path
+// elements of suffix.
+func uniqueSuffix(paths []string, p, suffix string) bool {
+	for _, other := range paths {
+		if other != p && (other == suffix || strings.HasSuffix(other, "/"+suffix)) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(fds []*base.FlowData, fd *base.FlowData) bool {
+	for _, f := range fds {
+		if f == fd {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/flowdev/ea-flow-doc/flow/base"
+)
+
+// Stats are the metrics of a flow.
+type Stats struct {
+	Package string // path of the package of the flow
+	Flow    string // name of the flow
+	// Steps is the number of calls and returns in all branches.
+	Steps int
+	// Depth is the deepest nesting of branches. A flow without branches
+	// has got a depth of 0.
+	Depth   int
+	Ports   int // number of output ports
+	Plugins int // number of plugins
+	FanIn   int // number of flows calling this flow
+	FanOut  int // number of subflows called by this flow
+	// LongestPath is the largest number of calls on a single way through
+	// the flow.
+	LongestPath int
+}
+
+// metrics returns the names and values of all metrics that can be limited.
+func (s Stats) metrics() []metric {
+	return []metric{
+		{"steps", s.Steps},
+		{"depth", s.Depth},
+		{"ports", s.Ports},
+		{"plugins", s.Plugins},
+		{"fanIn", s.FanIn},
+		{"fanOut", s.FanOut},
+		{"longestPath", s.LongestPath},
+	}
+}
+
+type metric struct {
+	name  string
+	value int
+}
+
+// FlowStats returns the metrics of all flows sorted by package path and
+// flow name.
+func FlowStats(flowDatas []*base.FlowData) []Stats {
+	ct := newCallTree(newIndex(flowDatas, "", PackageNaming{}), flowDatas)
+	fanIn := make(map[*base.FlowData]int, len(flowDatas))
+	for fd, calls := range ct.calls {
+		for _, sub := range calls {
+			if sub != fd {
+				fanIn[sub]++
+			}
+		}
+	}
+
+	stats := make([]Stats, len(flowDatas))
+	for i, fd := range flowDatas {
+		steps, depth := branchSize(fd.MainBranch)
+		stats[i] = Stats{
+			Package:     fd.PkgPath,
+			Flow:        FlowName(fd),
+			Steps:       steps,
+			Depth:       depth,
+			Ports:       len(fd.OutPorts),
+			Plugins:     len(fd.Plugins),
+			FanIn:       fanIn[fd],
+			FanOut:      len(ct.calls[fd]),
+			LongestPath: longestPath(fd.MainBranch, 0, false),
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Package != stats[j].Package {
+			return stats[i].Package < stats[j].Package
+		}
+		return stats[i].Flow < stats[j].Flow
+	})
+	return stats
+}
+
+// branchSize returns the number of steps in the branch and all of its
+// sub-branches and the depth of its deepest sub-branch.
+func branchSize(b *base.Branch) (steps, depth int) {
+	for _, step := range b.Steps {
+		if sub, ok := step.(*base.Branch); ok {
+			subSteps, subDepth := branchSize(sub)
+			steps += subSteps
+			depth = max(depth, subDepth+1)
+		} else {
+			steps++
+		}
+	}
+	return steps, depth
+}
+
+// longestPath returns the largest number of calls on a way through the
+// branch that starts after the given number of calls.
+// The parser puts the steps after the return of an if statement into its
+// branch, too. So they continue after the calls before the branch (cont)
+// unless this is the main branch.
+func longestPath(b *base.Branch, calls int, cont bool) int {
+	start, longest := calls, calls
+	for _, step := range b.Steps {
+		switch s := step.(type) {
+		case *base.CallStep:
+			calls++
+			longest = max(longest, calls)
+		case *base.ReturnStep:
+			if !cont {
+				return longest
+			}
+			calls, cont = start, false
+		case *base.Branch:
+			longest = max(longest, longestPath(s, calls, true))
+		}
+	}
+	return longest
+}
+
+// Limits are the maximum values of the metrics of a flow.
+// Metrics with a limit of 0 aren't limited.
+type Limits struct {
+	Steps       int `json:"steps"`
+	Depth       int `json:"depth"`
+	Ports       int `json:"ports"`
+	Plugins     int `json:"plugins"`
+	FanIn       int `json:"fanIn"`
+	FanOut      int `json:"fanOut"`
+	LongestPath int `json:"longestPath"`
+}
+
+// LoadLimits reads the limits from the JSON file with the given name.
+// The keys are the JSON names of the Limits fields.
+func LoadLimits(name string) (Limits, error) {
+	var l Limits
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		return l, fmt.Errorf("unable to read limits file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&l); err != nil {
+		return l, fmt.Errorf("unable to read limits file %q: %w", name, err)
+	}
+	return l, nil
+}
+
+// Violation is a metric of a flow that exceeds its limit.
+type Violation struct {
+	Package string
+	Flow    string
+	Metric  string // JSON name of the metric
+	Value   int
+	Limit   int
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("flow %q of package %q: %s is %d (limit: %d)", v.Flow, v.Package, v.Metric, v.Value, v.Limit)
+}
+
+// Check returns all metrics of the flows that exceed their limits.
+func (l Limits) Check(stats []Stats) []Violation {
+	limits := Stats{
+		Steps: l.Steps, Depth: l.Depth, Ports: l.Ports, Plugins: l.Plugins,
+		FanIn: l.FanIn, FanOut: l.FanOut, LongestPath: l.LongestPath,
+	}.metrics()
+	violations := make([]Violation, 0, 8)
+	for _, s := range stats {
+		for i, m := range s.metrics() {
+			if limit := limits[i].value; limit > 0 && m.value > limit {
+				violations = append(violations, Violation{
+					Package: s.Package,
+					Flow:    s.Flow,
+					Metric:  m.name,
+					Value:   m.value,
+					Limit:   limit,
+				})
+			}
+		}
+	}
+	return violations
+}
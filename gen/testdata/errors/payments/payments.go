@@ -0,0 +1,26 @@
+package payments
+
+// Bill is a bill of a customer.
+type Bill struct {
+	Amount int
+}
+
+// Pay checks and charges a bill.
+//
+//flowdev:flow
+func Pay(bill *Bill) (*Bill, error) {
+	checked, err := check(bill)
+	if err != nil {
+		return nil, err
+	}
+	paid := charge(checked)
+	return paid, nil
+}
+
+func check(b *Bill) (*Bill, error) {
+	return b, nil
+}
+
+func charge(b *Bill) *Bill {
+	return b
+}
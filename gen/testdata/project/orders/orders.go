@@ -0,0 +1,34 @@
+package orders
+
+// Order is an order of a customer.
+type Order struct {
+	ID string
+}
+
+// Store stores orders.
+type Store interface {
+	Save(*Order) error
+}
+
+// ProcessOrder validates and stores an order.
+// Invalid orders aren't stored.
+//
+//flowdev:flow
+func ProcessOrder(order *Order, pluginStore Store) *Order {
+	valid := validate(order)
+	if valid != nil {
+		return valid
+	}
+	//flowdev:group persistence
+	stored := store(order, pluginStore) //flowdev:note saves with retries
+	return stored
+}
+
+func validate(o *Order) *Order {
+	return o
+}
+
+func store(o *Order, s Store) *Order {
+	_ = s.Save(o)
+	return o
+}
@@ -0,0 +1,19 @@
+package shop
+
+import "example.com/project/orders"
+
+// Cart contains the articles a customer wants to buy.
+type Cart struct {
+	Articles []string
+}
+
+//flowdev:flow
+func Checkout(cart *Cart) *orders.Order {
+	order := newOrder(cart)
+	processed := orders.ProcessOrder(order, nil)
+	return processed
+}
+
+func newOrder(c *Cart) *orders.Order {
+	return &orders.Order{}
+}
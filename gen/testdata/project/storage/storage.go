@@ -0,0 +1,11 @@
+package storage
+
+import "example.com/project/orders"
+
+// DB stores orders in a database.
+type DB struct{}
+
+// Save stores the order.
+func (db *DB) Save(o *orders.Order) error {
+	return nil
+}
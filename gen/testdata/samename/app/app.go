@@ -0,0 +1,18 @@
+package app
+
+import (
+	helper "example.com/samename/b/util"
+	"example.com/samename/z/util"
+)
+
+//flowdev:flow
+func Run(d *helper.Data) *util.Data {
+	s := helper.Step(d)
+	c := convert(s)
+	m := util.Main(c)
+	return m
+}
+
+func convert(d *helper.Data) *util.Data {
+	return &util.Data{Value: d.Value}
+}
@@ -0,0 +1,16 @@
+package util
+
+// Data is passed through the flows.
+type Data struct {
+	Value int
+}
+
+//flowdev:flow
+func Step(d *Data) *Data {
+	r := inc(d)
+	return r
+}
+
+func inc(d *Data) *Data {
+	return &Data{Value: d.Value + 1}
+}
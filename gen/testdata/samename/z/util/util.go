@@ -0,0 +1,22 @@
+package util
+
+// Data is passed through the flows.
+type Data struct {
+	Value int
+}
+
+//flowdev:flow
+func Main(d *Data) *Data {
+	r := Step(d)
+	return r
+}
+
+//flowdev:flow
+func Step(d *Data) *Data {
+	r := double(d)
+	return r
+}
+
+func double(d *Data) *Data {
+	return &Data{Value: 2 * d.Value}
+}